@@ -0,0 +1,286 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/byuoitav/barrelman"
+)
+
+const _prefix = "/api/v1"
+
+// Server serves the status of a DeviceMonitor over HTTP as JSON
+type Server struct {
+	devices    barrelman.DeviceMonitor
+	rooms      barrelman.RoomMonitor
+	subscriber barrelman.CheckSubscriber
+
+	mux *http.ServeMux
+}
+
+// Option is a function which modifies a Server. This allows the user to set
+// options that have been exposed
+type Option func(*Server)
+
+// WithRoomMonitor allows the user to set a RoomMonitor whose room statuses
+// will also be served
+func WithRoomMonitor(rm barrelman.RoomMonitor) Option {
+	return func(s *Server) {
+		s.rooms = rm
+	}
+}
+
+// deviceResponse is the JSON representation of a device's status
+type deviceResponse struct {
+	Name    string                 `json:"name"`
+	Address string                 `json:"address"`
+	Room    string                 `json:"room"`
+	Healthy bool                   `json:"healthy"`
+	Checks  map[string]checkResult `json:"checks,omitempty"`
+}
+
+// checkResult is the JSON representation of a CheckResult
+type checkResult struct {
+	RunTime time.Time `json:"runTime"`
+	Passed  bool      `json:"passed"`
+	Skipped bool      `json:"skipped,omitempty"`
+	Message string    `json:"message,omitempty"`
+	Error   string    `json:"error,omitempty"`
+	Key     string    `json:"key,omitempty"`
+	Value   string    `json:"value,omitempty"`
+
+	Metrics map[string]metric `json:"metrics,omitempty"`
+
+	// Events maps the key of each additional event to its value
+	Events map[string]string `json:"events,omitempty"`
+}
+
+// metric is the JSON representation of a check's metric
+type metric struct {
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit,omitempty"`
+}
+
+// roomResponse is the JSON representation of a room's status
+type roomResponse struct {
+	Room            string              `json:"room"`
+	State           string              `json:"state"`
+	DeviceCount     int                 `json:"deviceCount"`
+	HealthyCount    int                 `json:"healthyCount"`
+	UnhealthyCount  int                 `json:"unhealthyCount"`
+	FailingCheckers map[string][]string `json:"failingCheckers,omitempty"`
+	Devices         []deviceResponse    `json:"devices"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewServer returns a new Server which serves the status of the given
+// DeviceMonitor with the given options set
+func NewServer(dm barrelman.DeviceMonitor, opts ...Option) (*Server, error) {
+	if dm == nil {
+		return nil, fmt.Errorf("A device monitor is required")
+	}
+
+	s := Server{
+		devices: dm,
+		mux:     http.NewServeMux(),
+	}
+
+	// Apply options
+	for _, opt := range opts {
+		opt(&s)
+	}
+
+	s.mux.HandleFunc(_prefix+"/devices", s.handleDevices)
+	s.mux.HandleFunc(_prefix+"/devices/", s.handleDevice)
+
+	// Stream updates if the monitor supports it
+	if sub, ok := dm.(barrelman.CheckSubscriber); ok {
+		s.subscriber = sub
+		s.mux.HandleFunc(_prefix+"/stream", s.handleStream)
+	}
+
+	if s.rooms != nil {
+		s.mux.HandleFunc(_prefix+"/rooms", s.handleRooms)
+		s.mux.HandleFunc(_prefix+"/rooms/", s.handleRoom)
+	}
+
+	return &s, nil
+}
+
+// ServeHTTP implements the http.Handler interface
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// handleDevices serves GET /api/v1/devices, which lists every device
+// and whether or not it is healthy
+func (s *Server) handleDevices(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("Method %s not allowed", r.Method))
+		return
+	}
+
+	statuses := s.devices.AllDevices()
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Device.Name < statuses[j].Device.Name
+	})
+
+	devs := make([]deviceResponse, 0, len(statuses))
+	for _, status := range statuses {
+		devs = append(devs, convertStatus(status, false))
+	}
+
+	writeJSON(w, http.StatusOK, devs)
+}
+
+// handleDevice serves GET /api/v1/devices/{name}, which returns the full
+// status of a device, and POST /api/v1/devices/{name}/check, which forces all
+// checks to be run on the device and then returns its status
+func (s *Server) handleDevice(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, _prefix+"/devices/"), "/")
+	name := parts[0]
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		// Nothing to do before returning the status
+	case len(parts) == 2 && parts[1] == "check" && r.Method == http.MethodPost:
+		if _, err := s.devices.Status(name); err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+
+		if err := s.devices.ForceCheck(name); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+	case len(parts) <= 2:
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("Method %s not allowed", r.Method))
+		return
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("Unknown path %s", r.URL.Path))
+		return
+	}
+
+	status, err := s.devices.Status(name)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, convertStatus(status, true))
+}
+
+// handleRooms serves GET /api/v1/rooms, which lists the status of every room
+func (s *Server) handleRooms(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("Method %s not allowed", r.Method))
+		return
+	}
+
+	statuses := s.rooms.AllRooms()
+	rooms := make([]roomResponse, 0, len(statuses))
+	for _, status := range statuses {
+		rooms = append(rooms, convertRoomStatus(status))
+	}
+
+	writeJSON(w, http.StatusOK, rooms)
+}
+
+// handleRoom serves GET /api/v1/rooms/{id}, which returns the status of a room
+func (s *Server) handleRoom(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("Method %s not allowed", r.Method))
+		return
+	}
+
+	status, err := s.rooms.RoomStatus(strings.TrimPrefix(r.URL.Path, _prefix+"/rooms/"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, convertRoomStatus(status))
+}
+
+func convertStatus(s barrelman.DeviceStatus, withChecks bool) deviceResponse {
+	d := deviceResponse{
+		Name:    s.Device.Name,
+		Address: s.Device.Address,
+		Room:    s.Device.Room,
+		Healthy: s.Healthy,
+	}
+
+	if !withChecks {
+		return d
+	}
+
+	d.Checks = make(map[string]checkResult, len(s.CheckStatus))
+	for name, result := range s.CheckStatus {
+		d.Checks[name] = convertResult(result)
+	}
+
+	return d
+}
+
+func convertResult(r barrelman.CheckResult) checkResult {
+	c := checkResult{
+		RunTime: r.RunTime,
+		Passed:  r.Passed,
+		Skipped: r.Skipped,
+		Message: r.Message,
+		Error:   r.Error,
+		Key:     r.Event.Key,
+		Value:   r.Event.Value,
+	}
+
+	if len(r.Metrics) > 0 {
+		c.Metrics = make(map[string]metric, len(r.Metrics))
+		for name, m := range r.Metrics {
+			c.Metrics[name] = metric{Value: m.Value, Unit: m.Unit}
+		}
+	}
+
+	if len(r.Events) > 0 {
+		c.Events = make(map[string]string, len(r.Events))
+		for _, e := range r.Events {
+			c.Events[e.Key] = e.Value
+		}
+	}
+
+	return c
+}
+
+func convertRoomStatus(s barrelman.RoomStatus) roomResponse {
+	room := roomResponse{
+		Room:            s.Room,
+		State:           string(s.State),
+		DeviceCount:     s.DeviceCount,
+		HealthyCount:    s.HealthyCount,
+		UnhealthyCount:  s.UnhealthyCount,
+		FailingCheckers: s.FailingCheckers,
+		Devices:         make([]deviceResponse, 0, len(s.Devices)),
+	}
+
+	for _, d := range s.Devices {
+		room.Devices = append(room.Devices, convertStatus(d, false))
+	}
+
+	return room
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, errorResponse{Error: err.Error()})
+}
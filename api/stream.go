@@ -0,0 +1,89 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/byuoitav/barrelman"
+)
+
+// _keepAlive is how often a comment is written to idle streams so that
+// proxies don't close the connection
+const _keepAlive = 15 * time.Second
+
+// updateResponse is the JSON representation of a CheckUpdate
+type updateResponse struct {
+	Device  string      `json:"device"`
+	Room    string      `json:"room"`
+	Checker string      `json:"checker"`
+	Healthy bool        `json:"healthy"`
+	Result  checkResult `json:"result"`
+}
+
+// handleStream serves GET /api/v1/stream, which streams every check result
+// recorded by the monitor to the client as Server-Sent Events. Passing a
+// device query parameter limits the stream to that device
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("Method %s not allowed", r.Method))
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("Streaming is not supported"))
+		return
+	}
+
+	device := r.URL.Query().Get("device")
+
+	updates := s.subscriber.Subscribe()
+	defer s.subscriber.Unsubscribe(updates)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(_keepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case u, ok := <-updates:
+			if !ok {
+				return
+			}
+
+			if device != "" && u.Device.Name != device {
+				continue
+			}
+
+			data, err := json.Marshal(convertUpdate(u))
+			if err != nil {
+				continue
+			}
+
+			fmt.Fprintf(w, "event: check\ndata: %s\n\n", data)
+		}
+
+		flusher.Flush()
+	}
+}
+
+func convertUpdate(u barrelman.CheckUpdate) updateResponse {
+	return updateResponse{
+		Device:  u.Device.Name,
+		Room:    u.Device.Room,
+		Checker: u.Checker,
+		Healthy: u.Healthy,
+		Result:  convertResult(u.Result),
+	}
+}
@@ -36,5 +36,9 @@ func (s *Service) Send(e barrelman.Event) {
 		Value:            e.Value,
 	}
 
+	if len(e.Metrics) > 0 {
+		newEvent.Data = convertMetrics(e.Metrics)
+	}
+
 	s.m.SendEvent(newEvent)
 }
@@ -31,6 +31,9 @@ func NewLogEmitter(hubAddress, systemID string) (*LogEventEmitter, error) {
 func (e *LogEventEmitter) Send(event barrelman.Event) {
 	// Log first
 	log.Printf("Event: Key: %s | Value: %s | Device: %s", event.Key, event.Value, event.Device.Name)
+	for name, m := range event.Metrics {
+		log.Printf("Metric: Name: %s | Value: %g%s | Device: %s", name, m.Value, m.Unit, event.Device.Name)
+	}
 
 	// Emit event to av central hub
 	devInfo := events.GenerateBasicDeviceInfo(event.Device.Name)
@@ -43,5 +46,9 @@ func (e *LogEventEmitter) Send(event barrelman.Event) {
 		Value:            event.Value,
 	}
 
+	if len(event.Metrics) > 0 {
+		newEvent.Data = convertMetrics(event.Metrics)
+	}
+
 	e.m.SendEvent(newEvent)
 }
@@ -0,0 +1,19 @@
+package avevent
+
+import "github.com/byuoitav/barrelman"
+
+// metric is how a metric is sent in the data of an event
+type metric struct {
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit,omitempty"`
+}
+
+// convertMetrics converts an event's metrics for the data of an av event
+func convertMetrics(metrics map[string]barrelman.Metric) map[string]metric {
+	data := make(map[string]metric, len(metrics))
+	for name, m := range metrics {
+		data[name] = metric{Value: m.Value, Unit: m.Unit}
+	}
+
+	return data
+}
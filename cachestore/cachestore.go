@@ -0,0 +1,422 @@
+package cachestore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/byuoitav/barrelman"
+)
+
+var _ barrelman.DeviceStore = (*Store)(nil)
+var _ barrelman.DeviceWatcher = (*Store)(nil)
+
+// Store is a DeviceStore which wraps another DeviceStore and persists the
+// last successful response of each request to disk. When the wrapped store
+// can't be reached the cached response is served instead, and the request is
+// retried in the background until the wrapped store can be reached again, or
+// until the Store is closed
+type Store struct {
+	// Options
+	retryInterval int
+
+	store barrelman.DeviceStore
+	path  string
+
+	// ctx is canceled when the store is closed, which stops any retries
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	cache    cache
+	retrying map[string]bool
+}
+
+// cache is the format of the cache file
+type cache struct {
+	Devices map[string]*barrelman.Device   `json:"devices"`
+	Rooms   map[string][]*barrelman.Device `json:"rooms"`
+	Central []*barrelman.Device            `json:"central"`
+}
+
+// Option is a function which modifies a Store. This allows the user to set
+// options that have been exposed
+type Option func(*Store)
+
+// WithRetryInterval allows the user to set how often (in seconds) a failed
+// request is retried against the wrapped store. The default is 30 seconds
+func WithRetryInterval(i int) Option {
+	return func(s *Store) {
+		s.retryInterval = i
+	}
+}
+
+// New returns a new Store which caches the responses of the given store in
+// the file at the given path. An existing cache file is loaded if it exists
+func New(store barrelman.DeviceStore, path string, opts ...Option) (*Store, error) {
+	if store == nil {
+		return nil, fmt.Errorf("A device store is required")
+	}
+
+	s := Store{
+		retryInterval: 30,
+		store:         store,
+		path:          path,
+		cache: cache{
+			Devices: make(map[string]*barrelman.Device),
+			Rooms:   make(map[string][]*barrelman.Device),
+		},
+		retrying: make(map[string]bool),
+	}
+
+	// Apply options
+	for _, opt := range opts {
+		opt(&s)
+	}
+
+	if s.retryInterval < 1 {
+		s.retryInterval = 1
+	}
+
+	data, err := ioutil.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, fmt.Errorf("Reading cache file: %w", err)
+	default:
+		if err := json.Unmarshal(data, &s.cache); err != nil {
+			log.Printf("Ignoring invalid cache file %s: %s", path, err)
+		}
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	return &s, nil
+}
+
+// Close stops retrying failed requests in the background. Cached devices are
+// still served after the store is closed, but are no longer refreshed unless
+// the wrapped store can be reached when they are requested
+func (s *Store) Close() error {
+	s.cancel()
+	return nil
+}
+
+// GetDevice returns the device with the given name
+func (s *Store) GetDevice(name string) (*barrelman.Device, error) {
+	return s.GetDeviceContext(context.Background(), name)
+}
+
+// GetDeviceContext returns the device with the given name
+func (s *Store) GetDeviceContext(ctx context.Context, name string) (*barrelman.Device, error) {
+	dev, err := s.refreshDevice(ctx, name)
+	if err == nil {
+		return dev, nil
+	}
+
+	s.mu.Lock()
+	cached, ok := s.cache.Devices[name]
+	s.mu.Unlock()
+
+	if !ok {
+		return nil, err
+	}
+
+	s.retry("device "+name, func(ctx context.Context) error {
+		_, err := s.refreshDevice(ctx, name)
+		return err
+	})
+
+	log.Printf("Serving cached device %s: %s", name, err)
+	return cached, nil
+}
+
+// GetRoomDevices returns all of the devices in the given room
+func (s *Store) GetRoomDevices(roomID string) ([]*barrelman.Device, error) {
+	return s.GetRoomDevicesContext(context.Background(), roomID)
+}
+
+// GetRoomDevicesContext returns all of the devices in the given room
+func (s *Store) GetRoomDevicesContext(ctx context.Context, roomID string) ([]*barrelman.Device, error) {
+	devs, err := s.refreshRoom(ctx, roomID)
+	if err == nil {
+		return devs, nil
+	}
+
+	s.mu.Lock()
+	cached, ok := s.cache.Rooms[roomID]
+	s.mu.Unlock()
+
+	if !ok {
+		return nil, err
+	}
+
+	s.retry("room "+roomID, func(ctx context.Context) error {
+		_, err := s.refreshRoom(ctx, roomID)
+		return err
+	})
+
+	log.Printf("Serving cached devices for room %s: %s", roomID, err)
+	return cached, nil
+}
+
+// GetCentralMonitoringDevice returns the central monitoring device with the
+// given name
+func (s *Store) GetCentralMonitoringDevice(name string) (*barrelman.Device, error) {
+	return s.GetCentralMonitoringDeviceContext(context.Background(), name)
+}
+
+// GetCentralMonitoringDeviceContext returns the central monitoring device
+// with the given name
+func (s *Store) GetCentralMonitoringDeviceContext(ctx context.Context, name string) (*barrelman.Device, error) {
+	devs, err := s.GetAllCentralMonitoringDevicesContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get all devices: %w", err)
+	}
+
+	for _, d := range devs {
+		if d.Name == name {
+			return d, nil
+		}
+	}
+
+	return nil, fmt.Errorf("Device not found")
+}
+
+// GetAllCentralMonitoringDevices returns all of the central monitoring devices
+func (s *Store) GetAllCentralMonitoringDevices() ([]*barrelman.Device, error) {
+	return s.GetAllCentralMonitoringDevicesContext(context.Background())
+}
+
+// GetAllCentralMonitoringDevicesContext returns all of the central monitoring
+// devices
+func (s *Store) GetAllCentralMonitoringDevicesContext(ctx context.Context) ([]*barrelman.Device, error) {
+	devs, err := s.refreshCentral(ctx)
+	if err == nil {
+		return devs, nil
+	}
+
+	s.mu.Lock()
+	cached := s.cache.Central
+	s.mu.Unlock()
+
+	if cached == nil {
+		return nil, err
+	}
+
+	s.retry("central monitoring devices", func(ctx context.Context) error {
+		_, err := s.refreshCentral(ctx)
+		return err
+	})
+
+	log.Printf("Serving cached central monitoring devices: %s", err)
+	return cached, nil
+}
+
+// refreshDevice gets the given device from the wrapped store and caches it
+func (s *Store) refreshDevice(ctx context.Context, name string) (*barrelman.Device, error) {
+	dev, err := s.store.GetDeviceContext(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	s.update(func(c *cache) { c.Devices[name] = dev })
+	return dev, nil
+}
+
+// refreshRoom gets the devices in the given room from the wrapped store and
+// caches them
+func (s *Store) refreshRoom(ctx context.Context, roomID string) ([]*barrelman.Device, error) {
+	devs, err := s.store.GetRoomDevicesContext(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.update(func(c *cache) { c.Rooms[roomID] = devs })
+	return devs, nil
+}
+
+// refreshCentral gets the central monitoring devices from the wrapped store
+// and caches them
+func (s *Store) refreshCentral(ctx context.Context) ([]*barrelman.Device, error) {
+	devs, err := s.store.GetAllCentralMonitoringDevicesContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.update(func(c *cache) { c.Central = devs })
+	return devs, nil
+}
+
+// update applies the given change to the cache and persists it to disk
+func (s *Store) update(change func(*cache)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	change(&s.cache)
+
+	if err := s.persist(); err != nil {
+		log.Printf("Failed to persist device cache: %s", err)
+	}
+}
+
+// persist writes the cache to disk. The file is written to a temporary file
+// first so that a crash never leaves a partial cache behind. The caller must
+// hold the lock
+func (s *Store) persist() error {
+	data, err := json.Marshal(s.cache)
+	if err != nil {
+		return fmt.Errorf("Marshaling cache: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("Creating cache directory: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("Writing cache file: %w", err)
+	}
+
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("Replacing cache file: %w", err)
+	}
+
+	return nil
+}
+
+// retry calls refresh on the retry interval in the background until it
+// succeeds or the store is closed. Only one retry runs for each key at a time
+func (s *Store) retry(key string, refresh func(context.Context) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.retrying[key] || s.ctx.Err() != nil {
+		return
+	}
+	s.retrying[key] = true
+
+	go func() {
+		defer func() {
+			s.mu.Lock()
+			delete(s.retrying, key)
+			s.mu.Unlock()
+		}()
+
+		interval := time.Duration(s.retryInterval) * time.Second
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+
+			ctx, cancel := context.WithTimeout(s.ctx, interval)
+			err := refresh(ctx)
+			cancel()
+
+			if err == nil {
+				log.Printf("Refreshed cached %s from device store", key)
+				return
+			}
+		}
+	}()
+}
+
+// WatchRoomDevices watches the wrapped store for changes to the devices in the
+// given room. The wrapped store must be a DeviceWatcher. If the wrapped store
+// can't be reached, watching is retried in the background, and any changes
+// made to the room while it was unreachable are sent once it is reached
+func (s *Store) WatchRoomDevices(ctx context.Context, roomID string) (<-chan barrelman.DeviceChange, error) {
+	w, ok := s.store.(barrelman.DeviceWatcher)
+	if !ok {
+		return nil, fmt.Errorf("Wrapped device store can't be watched")
+	}
+
+	s.mu.Lock()
+	served := s.cache.Rooms[roomID]
+	s.mu.Unlock()
+
+	return s.watch(ctx, "room "+roomID, served,
+		func(ctx context.Context) (<-chan barrelman.DeviceChange, error) {
+			return w.WatchRoomDevices(ctx, roomID)
+		},
+		func(ctx context.Context) ([]*barrelman.Device, error) {
+			return s.refreshRoom(ctx, roomID)
+		},
+	), nil
+}
+
+// WatchCentralMonitoringDevices watches the wrapped store for changes to the
+// central monitoring devices. The wrapped store must be a DeviceWatcher. If
+// the wrapped store can't be reached, watching is retried in the background,
+// and any changes made while it was unreachable are sent once it is reached
+func (s *Store) WatchCentralMonitoringDevices(ctx context.Context) (<-chan barrelman.DeviceChange, error) {
+	w, ok := s.store.(barrelman.DeviceWatcher)
+	if !ok {
+		return nil, fmt.Errorf("Wrapped device store can't be watched")
+	}
+
+	s.mu.Lock()
+	served := s.cache.Central
+	s.mu.Unlock()
+
+	return s.watch(ctx, "central monitoring devices", served, w.WatchCentralMonitoringDevices, s.refreshCentral), nil
+}
+
+// watch starts watching the wrapped store, retrying until it succeeds. Once
+// it succeeds the current devices are fetched and diffed against the devices
+// that were served from the cache, and then the wrapped store's changes are
+// passed through on the returned channel until the context is canceled
+func (s *Store) watch(ctx context.Context, key string, served []*barrelman.Device,
+	start func(context.Context) (<-chan barrelman.DeviceChange, error),
+	current func(context.Context) ([]*barrelman.Device, error)) <-chan barrelman.DeviceChange {
+	changes := make(chan barrelman.DeviceChange)
+
+	go func() {
+		defer close(changes)
+
+		var upstream <-chan barrelman.DeviceChange
+		for {
+			var err error
+			upstream, err = start(ctx)
+			if err == nil {
+				break
+			}
+
+			log.Printf("Failed to watch %s, retrying: %s", key, err)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Duration(s.retryInterval) * time.Second):
+			}
+		}
+
+		// Catch up on anything that changed while the store was unreachable
+		if devs, err := current(ctx); err == nil {
+			for _, c := range barrelman.DiffDevices(served, devs) {
+				select {
+				case changes <- c:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+
+		for c := range upstream {
+			select {
+			case changes <- c:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return changes
+}
@@ -0,0 +1,297 @@
+package cachestore
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/byuoitav/barrelman"
+)
+
+var _ barrelman.DeviceStore = (*upstream)(nil)
+var _ barrelman.DeviceWatcher = (*upstream)(nil)
+
+// upstream is a fake device store which can be made unreachable
+type upstream struct {
+	mu      sync.Mutex
+	down    bool
+	devices []*barrelman.Device
+	calls   int
+}
+
+func (u *upstream) setDown(down bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.down = down
+}
+
+func (u *upstream) setDevices(devs ...*barrelman.Device) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.devices = devs
+}
+
+func (u *upstream) callCount() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.calls
+}
+
+func (u *upstream) list() ([]*barrelman.Device, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.calls++
+	if u.down {
+		return nil, fmt.Errorf("store is unreachable")
+	}
+
+	return append([]*barrelman.Device(nil), u.devices...), nil
+}
+
+func (u *upstream) GetDevice(name string) (*barrelman.Device, error) {
+	return u.GetDeviceContext(context.Background(), name)
+}
+
+func (u *upstream) GetDeviceContext(ctx context.Context, name string) (*barrelman.Device, error) {
+	devs, err := u.list()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, d := range devs {
+		if d.Name == name {
+			return d, nil
+		}
+	}
+
+	return nil, fmt.Errorf("Device not found")
+}
+
+func (u *upstream) GetRoomDevices(roomID string) ([]*barrelman.Device, error) {
+	return u.GetRoomDevicesContext(context.Background(), roomID)
+}
+
+func (u *upstream) GetRoomDevicesContext(ctx context.Context, roomID string) ([]*barrelman.Device, error) {
+	return u.list()
+}
+
+func (u *upstream) GetCentralMonitoringDevice(name string) (*barrelman.Device, error) {
+	return u.GetDeviceContext(context.Background(), name)
+}
+
+func (u *upstream) GetCentralMonitoringDeviceContext(ctx context.Context, name string) (*barrelman.Device, error) {
+	return u.GetDeviceContext(ctx, name)
+}
+
+func (u *upstream) GetAllCentralMonitoringDevices() ([]*barrelman.Device, error) {
+	return u.list()
+}
+
+func (u *upstream) GetAllCentralMonitoringDevicesContext(ctx context.Context) ([]*barrelman.Device, error) {
+	return u.list()
+}
+
+func (u *upstream) WatchRoomDevices(ctx context.Context, roomID string) (<-chan barrelman.DeviceChange, error) {
+	return u.WatchCentralMonitoringDevices(ctx)
+}
+
+// WatchCentralMonitoringDevices never sends any changes, so that every change
+// received from the cache store comes from catching up
+func (u *upstream) WatchCentralMonitoringDevices(ctx context.Context) (<-chan barrelman.DeviceChange, error) {
+	if _, err := u.list(); err != nil {
+		return nil, err
+	}
+
+	changes := make(chan barrelman.DeviceChange)
+	go func() {
+		<-ctx.Done()
+		close(changes)
+	}()
+
+	return changes, nil
+}
+
+func device(name, address string) *barrelman.Device {
+	return &barrelman.Device{Name: name, Address: address, Room: "ITB-1101"}
+}
+
+func names(devs []*barrelman.Device) []string {
+	n := make([]string, 0, len(devs))
+	for _, d := range devs {
+		n = append(n, d.Name)
+	}
+
+	sort.Strings(n)
+	return n
+}
+
+// eventually waits up to a few retry intervals for cond to be true
+func eventually(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+		if cond() {
+			return
+		}
+	}
+
+	t.Fatalf("condition not met before deadline")
+}
+
+func TestServeCachedAndCatchUp(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "devices.json")
+	up := &upstream{}
+	up.setDevices(device("ITB-1101-D1", "10.0.0.1"), device("ITB-1101-D2", "10.0.0.2"))
+
+	// Populate the cache file while the store is reachable
+	s, err := New(up, path)
+	if err != nil {
+		t.Fatalf("failed to create store: %s", err)
+	}
+
+	if _, err := s.GetRoomDevices("ITB-1101"); err != nil {
+		t.Fatalf("failed to get room devices: %s", err)
+	}
+	s.Close()
+
+	// Restart while the store is unreachable, and change the room while it is
+	up.setDown(true)
+	up.setDevices(device("ITB-1101-D1", "10.0.0.11"), device("ITB-1101-D3", "10.0.0.3"))
+
+	s, err = New(up, path, WithRetryInterval(1))
+	if err != nil {
+		t.Fatalf("failed to create store: %s", err)
+	}
+	defer s.Close()
+
+	devs, err := s.GetRoomDevices("ITB-1101")
+	if err != nil {
+		t.Fatalf("expected cached devices, got error: %s", err)
+	}
+
+	if got := fmt.Sprint(names(devs)); got != "[ITB-1101-D1 ITB-1101-D2]" {
+		t.Fatalf("got cached devices %s", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes, err := s.WatchRoomDevices(ctx, "ITB-1101")
+	if err != nil {
+		t.Fatalf("failed to watch room devices: %s", err)
+	}
+
+	// Let the watch and the refresh fail at least once before recovering
+	before := up.callCount()
+	eventually(t, func() bool { return up.callCount() >= before+2 })
+	up.setDown(false)
+
+	got := make(map[string]barrelman.DeviceChangeType)
+	timeout := time.After(5 * time.Second)
+	for len(got) < 3 {
+		select {
+		case c := <-changes:
+			got[c.Device.Name] = c.Type
+		case <-timeout:
+			t.Fatalf("timed out waiting for changes, got %v", got)
+		}
+	}
+
+	want := map[string]barrelman.DeviceChangeType{
+		"ITB-1101-D1": barrelman.DeviceUpdated,
+		"ITB-1101-D2": barrelman.DeviceRemoved,
+		"ITB-1101-D3": barrelman.DeviceAdded,
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got changes %v, want %v", got, want)
+	}
+
+	// The background retry refreshes the cache, so the new devices are
+	// served the next time the store is unreachable
+	eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return len(s.retrying) == 0
+	})
+
+	up.setDown(true)
+
+	s, err = New(up, path)
+	if err != nil {
+		t.Fatalf("failed to create store: %s", err)
+	}
+	defer s.Close()
+
+	devs, err = s.GetRoomDevices("ITB-1101")
+	if err != nil {
+		t.Fatalf("expected cached devices, got error: %s", err)
+	}
+
+	if got := fmt.Sprint(names(devs)); got != "[ITB-1101-D1 ITB-1101-D3]" {
+		t.Errorf("got refreshed devices %s", got)
+	}
+}
+
+func TestNotCached(t *testing.T) {
+	up := &upstream{down: true}
+
+	s, err := New(up, filepath.Join(t.TempDir(), "devices.json"))
+	if err != nil {
+		t.Fatalf("failed to create store: %s", err)
+	}
+	defer s.Close()
+
+	if _, err := s.GetRoomDevices("ITB-1101"); err == nil {
+		t.Fatalf("expected an error with nothing cached")
+	}
+}
+
+func TestCloseStopsRetries(t *testing.T) {
+	up := &upstream{}
+	up.setDevices(device("ITB-1101-D1", "10.0.0.1"))
+
+	s, err := New(up, filepath.Join(t.TempDir(), "devices.json"), WithRetryInterval(1))
+	if err != nil {
+		t.Fatalf("failed to create store: %s", err)
+	}
+
+	if _, err := s.GetDevice("ITB-1101-D1"); err != nil {
+		t.Fatalf("failed to get device: %s", err)
+	}
+
+	up.setDown(true)
+	if _, err := s.GetDevice("ITB-1101-D1"); err != nil {
+		t.Fatalf("expected cached device, got error: %s", err)
+	}
+
+	s.Close()
+
+	eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return len(s.retrying) == 0
+	})
+
+	// Nothing should retry against the store once it's closed
+	calls := up.callCount()
+	time.Sleep(1500 * time.Millisecond)
+	if got := up.callCount(); got != calls {
+		t.Errorf("store was called %d times after closing", got-calls)
+	}
+
+	// New failures don't start retrying either
+	if _, err := s.GetDevice("ITB-1101-D1"); err != nil {
+		t.Fatalf("expected cached device, got error: %s", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.retrying) != 0 {
+		t.Errorf("expected no retries after closing, got %v", s.retrying)
+	}
+}
@@ -1,6 +1,9 @@
 package barrelman
 
-import "time"
+import (
+	"strconv"
+	"time"
+)
 
 // A Checker is used to "check" a specific aspect of a device for monitoring
 // purposes. Examples include:
@@ -27,6 +30,10 @@ type CheckResult struct {
 	// Passed is true if the check ran successfully and should be considered "passing"
 	Passed bool
 
+	// Skipped is true if the check was not run, e.g. because a check it
+	// depends on failed. Skipped checks are not considered failing
+	Skipped bool
+
 	// Message is an arbitrary message returned from the checker
 	Message string
 
@@ -37,4 +44,139 @@ type CheckResult struct {
 	// Event is the event that should be emitted (if an emitter is used)
 	// for the check
 	Event Event
+
+	// Metrics are numeric measurements taken during the check, such as
+	// round trip times, keyed by name
+	Metrics map[string]Metric
+
+	// Events are additional events to be emitted alongside Event, for
+	// checkers that report on several parts of a device at once
+	Events []Event
+}
+
+// Metric is a numeric measurement taken during a check
+type Metric struct {
+	Value float64
+
+	// Unit is the unit the value is measured in, e.g. ms or %, and is empty
+	// for counts and other unitless values
+	Unit string
+}
+
+// Failed returns true if the check ran and did not pass
+func (r CheckResult) Failed() bool {
+	return !r.Passed && !r.Skipped
+}
+
+// CheckerConfig is device specific configuration for a checker, as a map of
+// option name to value. Values are typically decoded from JSON, so the typed
+// getters below accept any reasonable representation of the requested type
+type CheckerConfig map[string]interface{}
+
+// Well known CheckerConfig options which are handled by monitors rather than
+// the checkers themselves
+const (
+	// ConfigEnabled is a bool which can be set to false to keep a checker from
+	// running on a device
+	ConfigEnabled = "enabled"
+
+	// ConfigInterval is the interval (in seconds) on which a checker should be
+	// run on a device, overriding the interval the checker was registered with
+	ConfigInterval = "checkInterval"
+)
+
+// Enabled returns false only if the config explicitly disables the checker
+func (c CheckerConfig) Enabled() bool {
+	enabled, ok := c.Bool(ConfigEnabled)
+	return !ok || enabled
+}
+
+// String returns the value of the given option as a string
+func (c CheckerConfig) String(key string) (string, bool) {
+	s, ok := c[key].(string)
+	return s, ok
+}
+
+// Float returns the value of the given option as a float64
+func (c CheckerConfig) Float(key string) (float64, bool) {
+	switch v := c[key].(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+
+	return 0, false
+}
+
+// Int returns the value of the given option as an int
+func (c CheckerConfig) Int(key string) (int, bool) {
+	f, ok := c.Float(key)
+	return int(f), ok
+}
+
+// Bool returns the value of the given option as a bool
+func (c CheckerConfig) Bool(key string) (bool, bool) {
+	switch v := c[key].(type) {
+	case bool:
+		return v, true
+	case string:
+		b, err := strconv.ParseBool(v)
+		return b, err == nil
+	}
+
+	return false, false
+}
+
+// Strings returns the value of the given option as a slice of strings
+func (c CheckerConfig) Strings(key string) ([]string, bool) {
+	switch v := c[key].(type) {
+	case []string:
+		return v, true
+	case string:
+		return []string{v}, true
+	case []interface{}:
+		strs := make([]string, 0, len(v))
+		for _, i := range v {
+			s, ok := i.(string)
+			if !ok {
+				return nil, false
+			}
+			strs = append(strs, s)
+		}
+		return strs, true
+	}
+
+	return nil, false
+}
+
+// Ints returns the value of the given option as a slice of ints
+func (c CheckerConfig) Ints(key string) ([]int, bool) {
+	switch v := c[key].(type) {
+	case []int:
+		return v, true
+	case []interface{}:
+		ints := make([]int, 0, len(v))
+		for _, i := range v {
+			n, ok := CheckerConfig{key: i}.Int(key)
+			if !ok {
+				return nil, false
+			}
+			ints = append(ints, n)
+		}
+		return ints, true
+	}
+
+	if i, ok := c.Int(key); ok {
+		return []int{i}, true
+	}
+
+	return nil, false
 }
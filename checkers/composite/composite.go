@@ -0,0 +1,160 @@
+package composite
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/byuoitav/barrelman"
+)
+
+// Mode is how the results of the checkers wrapped by a composite checker are
+// combined
+type Mode int
+
+const (
+	// All passes only if every checker passed or was skipped
+	All Mode = iota
+
+	// Any passes if at least one checker passed
+	Any
+)
+
+// Checker wraps one or more other checkers, combining their results and
+// optionally skipping them when a checker they depend on is failing
+type Checker struct {
+	mode     Mode
+	checkers []namedChecker
+
+	monitor      barrelman.DeviceMonitor
+	dependencies []string
+}
+
+type namedChecker struct {
+	name    string
+	checker barrelman.Checker
+}
+
+// NewChecker returns a composite checker with the given options set
+func NewChecker(opts ...Option) (*Checker, error) {
+	c := Checker{
+		mode: All,
+	}
+
+	// Apply options
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	if len(c.checkers) == 0 {
+		return nil, fmt.Errorf("At least one checker is required")
+	}
+
+	if c.mode != All && c.mode != Any {
+		return nil, fmt.Errorf("Invalid mode: %d", c.mode)
+	}
+
+	if len(c.dependencies) > 0 && c.monitor == nil {
+		return nil, fmt.Errorf("A monitor is required to check dependencies")
+	}
+
+	return &c, nil
+}
+
+// Check skips the device if one of the dependencies is failing, and otherwise
+// runs each of the wrapped checkers on it. When wrapping a single checker its
+// events are passed through unchanged, otherwise the events of every checker
+// are returned as additional events and their metrics are prefixed with the
+// checker's name
+func (c *Checker) Check(d *barrelman.Device, forceRecheck bool) barrelman.CheckResult {
+	if dep, failing := c.failingDependency(d); failing {
+		return barrelman.CheckResult{
+			RunTime: time.Now(),
+			Skipped: true,
+			Message: fmt.Sprintf("Skipped because %s is failing", dep),
+		}
+	}
+
+	if len(c.checkers) == 1 {
+		return c.checkers[0].checker.Check(d, forceRecheck)
+	}
+
+	result := barrelman.CheckResult{
+		RunTime: time.Now(),
+		Passed:  c.mode == All,
+		Skipped: true,
+	}
+
+	messages := []string{}
+	failures := []string{}
+	for _, nc := range c.checkers {
+		r := nc.checker.Check(d, forceRecheck)
+
+		if r.Event.Key != "" {
+			result.Events = append(result.Events, r.Event)
+		}
+		result.Events = append(result.Events, r.Events...)
+
+		for name, m := range r.Metrics {
+			if result.Metrics == nil {
+				result.Metrics = make(map[string]barrelman.Metric)
+			}
+			result.Metrics[nc.name+"."+name] = m
+		}
+
+		if r.Message != "" {
+			messages = append(messages, fmt.Sprintf("%s: %s", nc.name, r.Message))
+		}
+		if r.Error != "" {
+			failures = append(failures, fmt.Sprintf("%s: %s", nc.name, r.Error))
+		}
+
+		// Only skipped if every checker was skipped
+		if r.Skipped {
+			continue
+		}
+		result.Skipped = false
+
+		switch c.mode {
+		case All:
+			result.Passed = result.Passed && r.Passed
+		case Any:
+			result.Passed = result.Passed || r.Passed
+		}
+	}
+
+	if result.Skipped {
+		result.Passed = false
+	}
+
+	result.Message = strings.Join(messages, "; ")
+	if !result.Passed {
+		result.Error = strings.Join(failures, "; ")
+	}
+
+	return result
+}
+
+// failingDependency returns the first dependency whose latest result on the
+// device failed or was skipped. Dependencies that haven't run yet are not
+// considered failing
+func (c *Checker) failingDependency(d *barrelman.Device) (string, bool) {
+	if len(c.dependencies) == 0 {
+		return "", false
+	}
+
+	status, err := c.monitor.Status(d.Name)
+	if err != nil {
+		return "", false
+	}
+
+	// A skipped dependency didn't pass, the same as for NOfM, so skips cascade
+	// down a chain of dependencies
+	for _, dep := range c.dependencies {
+		if result, ok := status.CheckStatus[dep]; ok && !result.Passed {
+			return dep, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,33 @@
+package composite
+
+import "github.com/byuoitav/barrelman"
+
+// Option is a function which modifies a given checker, allowing the
+// user to have an option on how to setup the checker
+type Option func(*Checker)
+
+// WithChecker allows the user to add a checker to be run, under the given
+// name. Checkers are run in the order they are added
+func WithChecker(name string, c barrelman.Checker) Option {
+	return func(comp *Checker) {
+		comp.checkers = append(comp.checkers, namedChecker{name: name, checker: c})
+	}
+}
+
+// WithMode allows the user to set how the results of the checkers are
+// combined. The default is All
+func WithMode(m Mode) Option {
+	return func(c *Checker) {
+		c.mode = m
+	}
+}
+
+// WithDependencies allows the user to skip the checkers on a device when the
+// latest result from any of the given checkers, as recorded by the monitor,
+// failed or was skipped
+func WithDependencies(m barrelman.DeviceMonitor, checkers ...string) Option {
+	return func(c *Checker) {
+		c.monitor = m
+		c.dependencies = checkers
+	}
+}
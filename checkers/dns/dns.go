@@ -0,0 +1,196 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/byuoitav/barrelman"
+)
+
+// ConfigKey is the name of the device CheckerConfig read by the checker.
+// The resolver, timeout, maxLatency, and reverseCheck options override the
+// checker's own settings for the device. The addresses option pins the IPs
+// the device's hostname is expected to resolve to
+const ConfigKey = "dns"
+
+// Checker resolves the device's hostname to check that its DNS records
+// exist, are correct, and are served in a reasonable amount of time
+type Checker struct {
+	resolver   string
+	timeout    int
+	maxLatency int
+	reverse    bool
+}
+
+// NewChecker returns a dns checker with the given options set
+func NewChecker(opts ...Option) (*Checker, error) {
+	c := Checker{
+		timeout: 5,
+	}
+
+	// Apply options
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	if c.resolver != "" {
+		if _, _, err := net.SplitHostPort(c.resolver); err != nil {
+			return nil, fmt.Errorf("Invalid resolver address: %w", err)
+		}
+	}
+
+	return &c, nil
+}
+
+// Check looks up the device's hostname. If it resolves, matches any pinned
+// addresses, and (when enabled) every address points back to the hostname,
+// then the check is considered healthy. Devices addressed by IP are skipped
+func (c *Checker) Check(d *barrelman.Device, forceRecheck bool) barrelman.CheckResult {
+	result := barrelman.CheckResult{
+		RunTime: time.Now(),
+		Passed:  true,
+		Event: barrelman.Event{
+			Device: d,
+			Key:    "dns",
+			Value:  "Resolved",
+		},
+	}
+
+	fail := func(value, format string, a ...interface{}) barrelman.CheckResult {
+		result.Passed = false
+		result.Error = fmt.Sprintf(format, a...)
+		result.Event.Value = value
+		return result
+	}
+
+	if net.ParseIP(d.Address) != nil {
+		return barrelman.CheckResult{
+			RunTime: time.Now(),
+			Skipped: true,
+			Message: "Address is an IP, nothing to resolve",
+		}
+	}
+
+	// Apply any device specific settings
+	resolverAddr, timeout, maxLatency, reverse := c.resolver, c.timeout, c.maxLatency, c.reverse
+	conf := d.Config(ConfigKey)
+	if s, ok := conf.String("resolver"); ok {
+		resolverAddr = s
+	}
+	if i, ok := conf.Int("timeout"); ok && i > 0 {
+		timeout = i
+	}
+	if i, ok := conf.Int("maxLatency"); ok {
+		maxLatency = i
+	}
+	if b, ok := conf.Bool("reverseCheck"); ok {
+		reverse = b
+	}
+	pinned, _ := conf.Strings("addresses")
+
+	resolver := newResolver(resolverAddr)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	addrs, err := resolver.LookupHost(ctx, d.Address)
+	latency := time.Since(start)
+	if err != nil {
+		return fail("Unresolvable", "Failed to resolve %s: %s", d.Address, err)
+	}
+
+	result.Metrics = map[string]barrelman.Metric{
+		"resolve-time": {Value: float64(latency/time.Nanosecond) / 1000000, Unit: "ms"},
+		"addresses":    {Value: float64(len(addrs))},
+	}
+
+	result.Message = fmt.Sprintf(
+		"Resolved %s to %s in %fms",
+		d.Address,
+		strings.Join(addrs, ", "),
+		float64(latency/time.Nanosecond)/1000000, // Getting ms down to several decimal places
+	)
+
+	if len(pinned) > 0 {
+		if missing, extra := compare(pinned, addrs); len(missing) > 0 || len(extra) > 0 {
+			return fail("Mismatched", "Expected %s, got %s", strings.Join(pinned, ", "), strings.Join(addrs, ", "))
+		}
+	}
+
+	if reverse {
+		stale := []string{}
+		for _, addr := range addrs {
+			names, err := resolver.LookupAddr(ctx, addr)
+			if err != nil || !pointsTo(names, d.Address) {
+				stale = append(stale, addr)
+			}
+		}
+
+		if len(stale) > 0 {
+			return fail("Stale", "Reverse lookup of %s does not point back to %s", strings.Join(stale, ", "), d.Address)
+		}
+	}
+
+	if maxLatency > 0 && latency > time.Duration(maxLatency)*time.Millisecond {
+		return fail("Slow", "Resolution took longer than %dms", maxLatency)
+	}
+
+	return result
+}
+
+// newResolver returns a resolver which queries the given server, or the
+// system resolver if addr is empty
+func newResolver(addr string) *net.Resolver {
+	if addr == "" {
+		return net.DefaultResolver
+	}
+
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+}
+
+// compare returns the addresses in want that are missing from got, and the
+// addresses in got that are not in want
+func compare(want, got []string) (missing, extra []string) {
+	set := make(map[string]bool, len(got))
+	for _, g := range got {
+		set[net.ParseIP(g).String()] = true
+	}
+
+	for _, w := range want {
+		ip := net.ParseIP(w).String()
+		if !set[ip] {
+			missing = append(missing, w)
+		}
+		delete(set, ip)
+	}
+
+	for g := range set {
+		extra = append(extra, g)
+	}
+
+	return missing, extra
+}
+
+// pointsTo returns true if any of the names from a reverse lookup match the
+// hostname. A short hostname matches the first label of a fully qualified
+// name
+func pointsTo(names []string, host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSuffix(name, "."))
+		if name == host || strings.HasPrefix(name, host+".") {
+			return true
+		}
+	}
+
+	return false
+}
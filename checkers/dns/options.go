@@ -0,0 +1,38 @@
+package dns
+
+// Option is a function which modifies a given checker, allowing the
+// user to have an option on how to setup the checker
+type Option func(*Checker)
+
+// WithResolver allows the user to query a specific DNS server (host:port)
+// instead of the system resolver
+func WithResolver(addr string) Option {
+	return func(c *Checker) {
+		c.resolver = addr
+	}
+}
+
+// WithTimeout allows the user to set the timeout (in seconds) of each lookup.
+// The default is 5 seconds
+func WithTimeout(t int) Option {
+	return func(c *Checker) {
+		c.timeout = t
+	}
+}
+
+// WithMaxLatency allows the user to set the longest (in milliseconds) a
+// lookup may take before the check fails. 0, the default, disables the limit
+func WithMaxLatency(ms int) Option {
+	return func(c *Checker) {
+		c.maxLatency = ms
+	}
+}
+
+// WithReverseCheck allows the user to require that every resolved address
+// has a PTR record pointing back to the device's hostname, which catches
+// stale records left behind after a device is moved or readdressed
+func WithReverseCheck(check bool) Option {
+	return func(c *Checker) {
+		c.reverse = check
+	}
+}
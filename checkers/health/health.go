@@ -24,6 +24,12 @@ type deviceHealth struct {
 	Error   *string `json:"error"`
 }
 
+// ConfigKey is the name of the device CheckerConfig read by the checker.
+// The apiAddress option overrides the API the device's health is requested
+// from and the name option overrides the name the device is reported under
+// in the health response
+const ConfigKey = "health"
+
 // Option is an option for the checker
 type Option func(*Checker)
 
@@ -33,6 +39,7 @@ type Checker struct {
 	cacheTimeout int
 	sfGroup      singleflight.Group
 
+	// cache is keyed by the API address and room of the health request
 	cacheMu sync.RWMutex
 	cache   map[string]roomHealth
 }
@@ -40,11 +47,18 @@ type Checker struct {
 // NewChecker returns a new Health Checker which will hit the health endpoint
 // on the given API Address given the options passed in
 func NewChecker(apiAddress string, opts ...Option) (*Checker, error) {
-	return &Checker{
+	c := Checker{
 		apiAddress:   apiAddress,
 		cacheTimeout: 45, // 45 seconds by default
 		cache:        make(map[string]roomHealth),
-	}, nil
+	}
+
+	// Apply options
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return &c, nil
 }
 
 // Check will hit the AV Control API health endpoint for the given device.
@@ -61,16 +75,27 @@ func (c *Checker) Check(d *barrelman.Device, recheck bool) barrelman.CheckResult
 		},
 	}
 
+	// Apply any device specific settings
+	apiAddress, name := c.apiAddress, d.Name
+	conf := d.Config(ConfigKey)
+	if addr, ok := conf.String("apiAddress"); ok && addr != "" {
+		apiAddress = addr
+	}
+	if n, ok := conf.String("name"); ok && n != "" {
+		name = n
+	}
+
 	// Get cache entry for room
+	key := fmt.Sprintf("%s|%s", apiAddress, d.Room)
 	c.cacheMu.RLock()
-	rHealth, ok := c.cache[d.Room]
+	rHealth, ok := c.cache[key]
 	c.cacheMu.RUnlock()
 
 	// If we are forcing a recheck, the cache doesn't have an entry,
 	// or that entry is expired, then refresh the cache
 	if recheck || !ok || (ok && rHealth.Expires.Before(time.Now())) {
-		res, err, _ := c.sfGroup.Do(d.Room, func() (interface{}, error) {
-			return c.refreshRoomHealth(d.Room)
+		res, err, _ := c.sfGroup.Do(key, func() (interface{}, error) {
+			return c.refreshRoomHealth(apiAddress, d.Room, key)
 		})
 		if err != nil {
 			result.Error = err.Error()
@@ -81,8 +106,14 @@ func (c *Checker) Check(d *barrelman.Device, recheck bool) barrelman.CheckResult
 		rHealth = res.(roomHealth)
 	}
 
+	// Report how old the health information is
+	age := time.Duration(c.cacheTimeout)*time.Second - time.Until(rHealth.Expires)
+	result.Metrics = map[string]barrelman.Metric{
+		"health-age": {Value: age.Seconds(), Unit: "s"},
+	}
+
 	// If the device exists in the roomHealth
-	if devHealth, ok := rHealth.Devices[d.Name]; ok {
+	if devHealth, ok := rHealth.Devices[name]; ok {
 		// If the device had a health check
 		if devHealth.Healthy != nil {
 			// If the device is not healthy
@@ -112,8 +143,8 @@ func (c *Checker) Check(d *barrelman.Device, recheck bool) barrelman.CheckResult
 
 }
 
-func (c *Checker) refreshRoomHealth(room string) (roomHealth, error) {
-	url := fmt.Sprintf("%s/api/v1/room/%s/health", c.apiAddress, room)
+func (c *Checker) refreshRoomHealth(apiAddress, room, key string) (roomHealth, error) {
+	url := fmt.Sprintf("%s/api/v1/room/%s/health", apiAddress, room)
 
 	// Make health request
 	res, err := http.Get(url)
@@ -144,7 +175,7 @@ func (c *Checker) refreshRoomHealth(room string) (roomHealth, error) {
 	// Write to cache
 	h.Expires = time.Now().Add(time.Duration(c.cacheTimeout) * time.Second)
 	c.cacheMu.Lock()
-	c.cache[room] = h
+	c.cache[key] = h
 	c.cacheMu.Unlock()
 
 	return h, nil
@@ -0,0 +1,354 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	gohttp "net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/byuoitav/barrelman"
+)
+
+// ConfigKey is the name of the device CheckerConfig read by the checker.
+// The url, scheme, port, path, method, timeout, minStatus, maxStatus,
+// maxResponseTime, headers, bodyContains, bodyRegex, jsonPath, and jsonValue
+// options override the checker's own settings for the device
+const ConfigKey = "http"
+
+// _maxBodySize is the most of a response body that is read for assertions
+const _maxBodySize = 1 << 20
+
+// Checker makes an HTTP request to the device and checks the response
+// against the configured assertions
+type Checker struct {
+	scheme          string
+	port            int
+	path            string
+	method          string
+	timeout         int
+	insecure        bool
+	minStatus       int
+	maxStatus       int
+	maxResponseTime int
+	headers         map[string]string
+	bodyContains    string
+	bodyRegexExpr   string
+	jsonPath        string
+	jsonValue       string
+
+	client    *gohttp.Client
+	bodyRegex *regexp.Regexp
+
+	// regexes caches the body regexes set by devices, keyed by expression
+	regexesMu sync.Mutex
+	regexes   map[string]compiledRegex
+}
+
+// compiledRegex is the result of compiling a body regex
+type compiledRegex struct {
+	re  *regexp.Regexp
+	err error
+}
+
+// request is the request and assertions for a single device, after any
+// device specific settings have been applied
+type request struct {
+	url             string
+	method          string
+	timeout         time.Duration
+	minStatus       int
+	maxStatus       int
+	maxResponseTime time.Duration
+	headers         map[string]string
+	bodyContains    string
+	bodyRegex       *regexp.Regexp
+	jsonPath        string
+	jsonValue       string
+}
+
+// NewChecker returns an http checker with the given options set
+func NewChecker(opts ...Option) (*Checker, error) {
+	c := Checker{
+		scheme:    "http",
+		path:      "/",
+		method:    gohttp.MethodGet,
+		timeout:   10,
+		minStatus: 200,
+		maxStatus: 299,
+		headers:   make(map[string]string),
+		regexes:   make(map[string]compiledRegex),
+	}
+
+	// Apply options
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	if c.bodyRegexExpr != "" {
+		re, err := regexp.Compile(c.bodyRegexExpr)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid body regex: %w", err)
+		}
+
+		c.bodyRegex = re
+	}
+
+	c.client = &gohttp.Client{
+		Transport: &gohttp.Transport{
+			Proxy:           gohttp.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: c.insecure},
+		},
+	}
+
+	return &c, nil
+}
+
+// Check makes the configured request to the given device. If the response
+// meets all of the configured assertions then the check is considered
+// healthy. A failed request or any failed assertion will result in an
+// unhealthy check
+func (c *Checker) Check(d *barrelman.Device, forceRecheck bool) barrelman.CheckResult {
+	result := barrelman.CheckResult{
+		RunTime: time.Now(),
+		Passed:  true,
+		Event: barrelman.Event{
+			Device: d,
+			Key:    "http",
+			Value:  "Ok",
+		},
+	}
+
+	fail := func(format string, a ...interface{}) barrelman.CheckResult {
+		result.Passed = false
+		result.Error = fmt.Sprintf(format, a...)
+		result.Event.Value = "Failed"
+		return result
+	}
+
+	req, err := c.request(d)
+	if err != nil {
+		return fail("Invalid config: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), req.timeout)
+	defer cancel()
+
+	httpReq, err := gohttp.NewRequestWithContext(ctx, req.method, req.url, nil)
+	if err != nil {
+		return fail("Failed to create request: %s", err)
+	}
+
+	start := time.Now()
+	res, err := c.client.Do(httpReq)
+	if err != nil {
+		return fail("Failed to make request: %s", err)
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, _maxBodySize))
+	if err != nil {
+		return fail("Failed to read response body: %s", err)
+	}
+	elapsed := time.Since(start)
+
+	result.Metrics = map[string]barrelman.Metric{
+		"response-time": {Value: float64(elapsed/time.Nanosecond) / 1000000, Unit: "ms"},
+		"status-code":   {Value: float64(res.StatusCode)},
+		"body-size":     {Value: float64(len(body)), Unit: "bytes"},
+	}
+
+	result.Message = fmt.Sprintf(
+		"%s %s returned %d in %fms",
+		req.method, req.url, res.StatusCode,
+		float64(elapsed/time.Nanosecond)/1000000, // Getting ms down to several decimal places
+	)
+
+	// Check all of the assertions
+	if res.StatusCode < req.minStatus || res.StatusCode > req.maxStatus {
+		return fail("Got status %d, expected %d-%d", res.StatusCode, req.minStatus, req.maxStatus)
+	}
+
+	if req.maxResponseTime > 0 && elapsed > req.maxResponseTime {
+		return fail("Response took %s, longer than %s", elapsed, req.maxResponseTime)
+	}
+
+	for name, value := range req.headers {
+		if got := res.Header.Get(name); got != value {
+			return fail("Got header %s: %q, expected %q", name, got, value)
+		}
+	}
+
+	if req.bodyContains != "" && !strings.Contains(string(body), req.bodyContains) {
+		return fail("Response body does not contain %q", req.bodyContains)
+	}
+
+	if req.bodyRegex != nil && !req.bodyRegex.Match(body) {
+		return fail("Response body does not match %q", req.bodyRegex)
+	}
+
+	if req.jsonPath != "" {
+		var v interface{}
+		if err := json.Unmarshal(body, &v); err != nil {
+			return fail("Failed to parse response body: %s", err)
+		}
+
+		got, err := lookup(v, req.jsonPath)
+		if err != nil {
+			return fail("Failed to find %s in response body: %s", req.jsonPath, err)
+		}
+
+		if fmt.Sprint(got) != req.jsonValue {
+			return fail("Got %v at %s, expected %s", got, req.jsonPath, req.jsonValue)
+		}
+	}
+
+	return result
+}
+
+// request builds the request for the given device by applying any device
+// specific settings to the checker's settings
+func (c *Checker) request(d *barrelman.Device) (request, error) {
+	conf := d.Config(ConfigKey)
+
+	scheme, port, path := c.scheme, c.port, c.path
+	if s, ok := conf.String("scheme"); ok {
+		scheme = s
+	}
+	if p, ok := conf.Int("port"); ok {
+		port = p
+	}
+	if p, ok := conf.String("path"); ok {
+		path = p
+	}
+
+	host := d.Address
+	if port > 0 {
+		host = net.JoinHostPort(d.Address, strconv.Itoa(port))
+	}
+
+	req := request{
+		url:             fmt.Sprintf("%s://%s/%s", scheme, host, strings.TrimPrefix(path, "/")),
+		method:          c.method,
+		timeout:         time.Duration(c.timeout) * time.Second,
+		minStatus:       c.minStatus,
+		maxStatus:       c.maxStatus,
+		maxResponseTime: time.Duration(c.maxResponseTime) * time.Millisecond,
+		headers:         c.headers,
+		bodyContains:    c.bodyContains,
+		jsonPath:        c.jsonPath,
+		jsonValue:       c.jsonValue,
+	}
+
+	if u, ok := conf.String("url"); ok {
+		req.url = u
+	}
+	if m, ok := conf.String("method"); ok {
+		req.method = m
+	}
+	if t, ok := conf.Int("timeout"); ok && t > 0 {
+		req.timeout = time.Duration(t) * time.Second
+	}
+	if s, ok := conf.Int("minStatus"); ok {
+		req.minStatus = s
+	}
+	if s, ok := conf.Int("maxStatus"); ok {
+		req.maxStatus = s
+	}
+	if ms, ok := conf.Int("maxResponseTime"); ok {
+		req.maxResponseTime = time.Duration(ms) * time.Millisecond
+	}
+	if h, ok := conf["headers"].(map[string]interface{}); ok {
+		req.headers = make(map[string]string, len(h))
+		for name, value := range h {
+			req.headers[name] = fmt.Sprint(value)
+		}
+	}
+	if s, ok := conf.String("bodyContains"); ok {
+		req.bodyContains = s
+	}
+	if p, ok := conf.String("jsonPath"); ok {
+		req.jsonPath = p
+	}
+	if v, ok := conf["jsonValue"]; ok {
+		req.jsonValue = fmt.Sprint(v)
+	}
+
+	req.bodyRegex = c.bodyRegex
+	if r, ok := conf.String("bodyRegex"); ok {
+		re, err := c.deviceRegex(r)
+		if err != nil {
+			return request{}, fmt.Errorf("invalid body regex: %w", err)
+		}
+
+		req.bodyRegex = re
+	}
+
+	return req, nil
+}
+
+// deviceRegex returns the compiled body regex set by a device. Each
+// expression is only compiled once, since devices are checked repeatedly
+func (c *Checker) deviceRegex(expr string) (*regexp.Regexp, error) {
+	if expr == "" {
+		return nil, nil
+	}
+
+	c.regexesMu.Lock()
+	defer c.regexesMu.Unlock()
+
+	compiled, ok := c.regexes[expr]
+	if !ok {
+		compiled.re, compiled.err = regexp.Compile(expr)
+		c.regexes[expr] = compiled
+	}
+
+	return compiled.re, compiled.err
+}
+
+// lookup finds the value at the given path in a decoded JSON value. Paths are
+// dot separated keys and [n] array indexes, optionally prefixed with $
+func lookup(v interface{}, path string) (interface{}, error) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	path = strings.ReplaceAll(path, "[", ".[")
+
+	for _, part := range strings.Split(path, ".") {
+		if part == "" {
+			continue
+		}
+
+		if strings.HasPrefix(part, "[") && strings.HasSuffix(part, "]") {
+			i, err := strconv.Atoi(part[1 : len(part)-1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid index %s", part)
+			}
+
+			arr, ok := v.([]interface{})
+			if !ok || i < 0 || i >= len(arr) {
+				return nil, fmt.Errorf("index %s not found", part)
+			}
+
+			v = arr[i]
+			continue
+		}
+
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("key %s not found", part)
+		}
+
+		if v, ok = obj[part]; !ok {
+			return nil, fmt.Errorf("key %s not found", part)
+		}
+	}
+
+	return v, nil
+}
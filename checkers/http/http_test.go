@@ -0,0 +1,95 @@
+package http
+
+import (
+	"fmt"
+	"net"
+	gohttp "net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/byuoitav/barrelman"
+)
+
+// newServer starts a server which responds to every request with body
+func newServer(t *testing.T, body string) int {
+	t.Helper()
+
+	srv := httptest.NewServer(gohttp.HandlerFunc(func(w gohttp.ResponseWriter, r *gohttp.Request) {
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(srv.Close)
+
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	p, _ := strconv.Atoi(port)
+	return p
+}
+
+func testDevice(conf barrelman.CheckerConfig) *barrelman.Device {
+	return &barrelman.Device{
+		Name:          "ITB-1101-CP1",
+		Address:       "127.0.0.1",
+		CheckerConfig: map[string]barrelman.CheckerConfig{ConfigKey: conf},
+	}
+}
+
+func TestCheckBodyRegex(t *testing.T) {
+	port := newServer(t, `{"status": "ok", "uptime": 1234}`)
+
+	c, err := NewChecker(WithPort(port), WithTimeout(1), WithBodyRegex(`"status":\s*"ok"`))
+	if err != nil {
+		t.Fatalf("failed to create checker: %s", err)
+	}
+
+	tests := []struct {
+		name string
+		conf barrelman.CheckerConfig
+		err  string
+	}{
+		{name: "checker regex"},
+		{name: "device regex", conf: barrelman.CheckerConfig{"bodyRegex": `"uptime":\s*\d+`}},
+		{name: "device regex cleared", conf: barrelman.CheckerConfig{"bodyRegex": ""}},
+		{
+			name: "device regex mismatch",
+			conf: barrelman.CheckerConfig{"bodyRegex": `"status":\s*"degraded"`},
+			err:  `Response body does not match "\"status\":\\s*\"degraded\""`,
+		},
+		{
+			name: "invalid device regex",
+			conf: barrelman.CheckerConfig{"bodyRegex": `(`},
+			err:  "Invalid config: invalid body regex: error parsing regexp: missing closing ): `(`",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Check twice so that cached regexes are used
+			for i := 0; i < 2; i++ {
+				conf := barrelman.CheckerConfig{"port": port}
+				for k, v := range tt.conf {
+					conf[k] = v
+				}
+
+				result := c.Check(testDevice(conf), false)
+				if result.Passed != (tt.err == "") {
+					t.Fatalf("got passed %t, error %q", result.Passed, result.Error)
+				}
+
+				if result.Error != tt.err {
+					t.Errorf("got error %q, want %q", result.Error, tt.err)
+				}
+			}
+		})
+	}
+
+	// An empty regex clears the checker's regex and isn't compiled
+	if len(c.regexes) != 3 {
+		t.Errorf("expected 3 cached device regexes, got %d", len(c.regexes))
+	}
+}
+
+func TestNewCheckerInvalidRegex(t *testing.T) {
+	if _, err := NewChecker(WithBodyRegex(`[a-`)); err == nil {
+		t.Fatalf("expected an error for an invalid body regex")
+	}
+}
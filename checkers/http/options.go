@@ -0,0 +1,106 @@
+package http
+
+// Option is a function which modifies a given checker, allowing the
+// user to have an option on how to setup the checker
+type Option func(*Checker)
+
+// WithScheme allows the user to set the scheme of the request. The default
+// is http
+func WithScheme(s string) Option {
+	return func(c *Checker) {
+		c.scheme = s
+	}
+}
+
+// WithPort allows the user to set the port of the request. The default is
+// the default port for the scheme
+func WithPort(p int) Option {
+	return func(c *Checker) {
+		c.port = p
+	}
+}
+
+// WithPath allows the user to set the path (and query) of the request. The
+// default is /
+func WithPath(p string) Option {
+	return func(c *Checker) {
+		c.path = p
+	}
+}
+
+// WithMethod allows the user to set the method of the request. The default
+// is GET
+func WithMethod(m string) Option {
+	return func(c *Checker) {
+		c.method = m
+	}
+}
+
+// WithTimeout allows the user to set the overall timeout of the request in
+// seconds. The default is 10 seconds
+func WithTimeout(t int) Option {
+	return func(c *Checker) {
+		c.timeout = t
+	}
+}
+
+// WithInsecureSkipVerify allows the user to skip verification of the
+// device's TLS certificate, which is common on device web interfaces
+func WithInsecureSkipVerify(skip bool) Option {
+	return func(c *Checker) {
+		c.insecure = skip
+	}
+}
+
+// WithStatusRange allows the user to set the inclusive range of status codes
+// considered passing. The default is 200 to 299
+func WithStatusRange(min, max int) Option {
+	return func(c *Checker) {
+		c.minStatus = min
+		c.maxStatus = max
+	}
+}
+
+// WithMaxResponseTime allows the user to set the longest time (in
+// milliseconds) the request may take before the check fails. The default of
+// 0 disables the assertion
+func WithMaxResponseTime(ms int) Option {
+	return func(c *Checker) {
+		c.maxResponseTime = ms
+	}
+}
+
+// WithHeader allows the user to require that the response has a header with
+// the given value
+func WithHeader(name, value string) Option {
+	return func(c *Checker) {
+		c.headers[name] = value
+	}
+}
+
+// WithBodyContains allows the user to require that the response body
+// contains the given string
+func WithBodyContains(s string) Option {
+	return func(c *Checker) {
+		c.bodyContains = s
+	}
+}
+
+// WithBodyRegex allows the user to require that the response body matches
+// the given regular expression
+func WithBodyRegex(re string) Option {
+	return func(c *Checker) {
+		c.bodyRegexExpr = re
+	}
+}
+
+// WithJSONPath allows the user to require that the value at the given path
+// in the JSON response body equals the given value. Paths are dot separated
+// keys and [n] array indexes, optionally prefixed with $, for example
+// $.devices[0].healthy
+func WithJSONPath(path, value string) Option {
+	return func(c *Checker) {
+		c.jsonPath = path
+		c.jsonValue = value
+	}
+}
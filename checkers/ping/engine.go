@@ -0,0 +1,294 @@
+package ping
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-ping/ping"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+const (
+	// _protocolICMP is the IANA protocol number of ICMP for IPv4
+	_protocolICMP = 1
+
+	// _readBuffer is the size (in bytes) of the receive buffer requested for
+	// raw sockets
+	_readBuffer = 4 << 20
+)
+
+// engines holds the one engine per privilege mode shared by every checker in
+// the process. Raw sockets see every ICMP packet on the host, so two engines
+// with the same ID would steal each other's replies
+var (
+	enginesMu sync.Mutex
+	engines   = make(map[bool]*engine)
+)
+
+// engine multiplexes the pings of every check through a single ICMP socket,
+// matching replies to requests by their ID and sequence number. This keeps
+// the number of open sockets constant no matter how many devices are checked
+// at once
+type engine struct {
+	conn       net.PacketConn
+	privileged bool
+
+	// id is sent with every request. It is the process ID so that engines
+	// in other processes ignore our replies. Unprivileged sockets have theirs
+	// rewritten by the kernel, which also filters replies for us
+	id int
+
+	mu      sync.Mutex
+	seq     uint16
+	pending map[uint16]request
+}
+
+// request is an echo request waiting for a reply
+type request struct {
+	ip      net.IP
+	sent    time.Time
+	replies chan<- time.Duration
+}
+
+// sharedEngine returns the process's engine for the given privilege mode,
+// opening it the first time it is requested
+func sharedEngine(privileged bool) (*engine, error) {
+	enginesMu.Lock()
+	defer enginesMu.Unlock()
+
+	if e, ok := engines[privileged]; ok {
+		return e, nil
+	}
+
+	e, err := newEngine(privileged)
+	if err != nil {
+		return nil, err
+	}
+
+	engines[privileged] = e
+	return e, nil
+}
+
+// newEngine opens the engine's socket and starts reading replies from it
+func newEngine(privileged bool) (*engine, error) {
+	var conn net.PacketConn
+	if privileged {
+		ipConn, err := net.ListenIP("ip4:icmp", &net.IPAddr{IP: net.IPv4zero})
+		if err != nil {
+			return nil, fmt.Errorf("Unable to open ICMP socket: %w", err)
+		}
+
+		// Raw sockets receive every ICMP packet on the host, so give them room
+		// for bursts of replies. The kernel caps this at net.core.rmem_max
+		if err := ipConn.SetReadBuffer(_readBuffer); err != nil {
+			log.Printf("Failed to set ICMP socket read buffer: %s", err)
+		}
+
+		conn = ipConn
+	} else {
+		icmpConn, err := icmp.ListenPacket("udp4", "0.0.0.0")
+		if err != nil {
+			return nil, fmt.Errorf("Unable to open ICMP socket: %w", err)
+		}
+
+		conn = icmpConn
+	}
+
+	e := &engine{
+		conn:       conn,
+		privileged: privileged,
+		id:         os.Getpid() & 0xffff,
+		pending:    make(map[uint16]request),
+	}
+
+	go e.read()
+	return e, nil
+}
+
+// ping sends count echo requests to ip, one every interval, and waits for
+// the replies until they have all returned or the timeout is reached
+func (e *engine) ping(ip net.IP, count int, interval, timeout time.Duration) *ping.Statistics {
+	stats := &ping.Statistics{
+		IPAddr: &net.IPAddr{IP: ip},
+		Addr:   ip.String(),
+	}
+
+	replies := make(chan time.Duration, count)
+	seqs := []uint16{}
+	defer func() {
+		e.mu.Lock()
+		for _, seq := range seqs {
+			delete(e.pending, seq)
+		}
+		e.mu.Unlock()
+	}()
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	send := func() {
+		seq, err := e.send(ip, replies)
+		if err != nil {
+			log.Printf("Failed to send ping to %s: %s", ip, err)
+		} else {
+			seqs = append(seqs, seq)
+		}
+
+		// Failed sends count as lost pings
+		stats.PacketsSent++
+	}
+
+	send()
+	for stats.PacketsRecv < count {
+		select {
+		case rtt := <-replies:
+			stats.PacketsRecv++
+			stats.Rtts = append(stats.Rtts, rtt)
+		case <-ticker.C:
+			if stats.PacketsSent < count {
+				send()
+			}
+		case <-deadline.C:
+			return summarize(stats)
+		}
+	}
+
+	return summarize(stats)
+}
+
+// send writes a single echo request to ip, registering replies as the
+// channel its reply should be sent on
+func (e *engine) send(ip net.IP, replies chan<- time.Duration) (uint16, error) {
+	e.mu.Lock()
+	if len(e.pending) > math.MaxUint16 {
+		e.mu.Unlock()
+		return 0, fmt.Errorf("too many pings in flight")
+	}
+
+	// Find the next free sequence number
+	e.seq++
+	for _, ok := e.pending[e.seq]; ok; _, ok = e.pending[e.seq] {
+		e.seq++
+	}
+
+	seq := e.seq
+	e.pending[seq] = request{ip: ip, sent: time.Now(), replies: replies}
+	e.mu.Unlock()
+
+	msg := icmp.Message{
+		Type: ipv4.ICMPTypeEcho,
+		Body: &icmp.Echo{
+			ID:   e.id,
+			Seq:  int(seq),
+			Data: []byte("barrelman"),
+		},
+	}
+
+	b, err := msg.Marshal(nil)
+	if err != nil {
+		return 0, err
+	}
+
+	var dst net.Addr = &net.UDPAddr{IP: ip}
+	if e.privileged {
+		dst = &net.IPAddr{IP: ip}
+	}
+
+	if _, err := e.conn.WriteTo(b, dst); err != nil {
+		e.mu.Lock()
+		delete(e.pending, seq)
+		e.mu.Unlock()
+		return 0, err
+	}
+
+	return seq, nil
+}
+
+// read matches every echo reply received on the socket to its request
+func (e *engine) read() {
+	buf := make([]byte, 1500)
+	for {
+		n, peer, err := e.conn.ReadFrom(buf)
+		if err != nil {
+			log.Printf("Failed to read from ICMP socket: %s", err)
+			time.Sleep(time.Second)
+			continue
+		}
+		received := time.Now()
+
+		msg, err := icmp.ParseMessage(_protocolICMP, buf[:n])
+		if err != nil || msg.Type != ipv4.ICMPTypeEchoReply {
+			continue
+		}
+
+		echo, ok := msg.Body.(*icmp.Echo)
+		if !ok || (e.privileged && echo.ID != e.id) {
+			continue
+		}
+
+		var from net.IP
+		switch addr := peer.(type) {
+		case *net.IPAddr:
+			from = addr.IP
+		case *net.UDPAddr:
+			from = addr.IP
+		}
+
+		seq := uint16(echo.Seq)
+		e.mu.Lock()
+		req, ok := e.pending[seq]
+		if ok && req.ip.Equal(from) {
+			delete(e.pending, seq)
+		} else {
+			ok = false
+		}
+		e.mu.Unlock()
+
+		if ok {
+			req.replies <- received.Sub(req.sent)
+		}
+	}
+}
+
+// summarize computes the loss and round trip time statistics the same way
+// go-ping does
+func summarize(stats *ping.Statistics) *ping.Statistics {
+	if stats.PacketsSent > 0 {
+		stats.PacketLoss = float64(stats.PacketsSent-stats.PacketsRecv) / float64(stats.PacketsSent) * 100
+	}
+
+	if len(stats.Rtts) == 0 {
+		return stats
+	}
+
+	var total time.Duration
+	stats.MinRtt = stats.Rtts[0]
+	for _, rtt := range stats.Rtts {
+		if rtt < stats.MinRtt {
+			stats.MinRtt = rtt
+		}
+		if rtt > stats.MaxRtt {
+			stats.MaxRtt = rtt
+		}
+		total += rtt
+	}
+
+	stats.AvgRtt = total / time.Duration(len(stats.Rtts))
+
+	var sumSquares time.Duration
+	for _, rtt := range stats.Rtts {
+		sumSquares += (rtt - stats.AvgRtt) * (rtt - stats.AvgRtt)
+	}
+	stats.StdDevRtt = time.Duration(math.Sqrt(float64(sumSquares / time.Duration(len(stats.Rtts)))))
+
+	return stats
+}
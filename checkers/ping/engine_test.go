@@ -0,0 +1,194 @@
+package ping
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-ping/ping"
+)
+
+var _loopback = net.IPv4(127, 0, 0, 1).To4()
+
+// benchmarkParallelism is how many goroutines per CPU ping at once, roughly
+// matching a monitor firing many devices' checks together
+const benchmarkParallelism = 64
+
+// privileged returns the ICMP mode available to the tests, skipping the test
+// if no ICMP socket can be opened
+func privileged(tb testing.TB) bool {
+	tb.Helper()
+
+	if err := canListen(true); err == nil {
+		return true
+	}
+
+	if err := canListen(false); err == nil {
+		return false
+	}
+
+	tb.Skip("unable to open an ICMP socket")
+	return false
+}
+
+func TestSharedEngine(t *testing.T) {
+	p := privileged(t)
+
+	a, err := sharedEngine(p)
+	if err != nil {
+		t.Fatalf("failed to open engine: %s", err)
+	}
+
+	b, err := sharedEngine(p)
+	if err != nil {
+		t.Fatalf("failed to open engine: %s", err)
+	}
+
+	if a != b {
+		t.Fatalf("expected the same engine for the same privilege mode")
+	}
+}
+
+func TestSharedCheckers(t *testing.T) {
+	p := privileged(t)
+
+	a, err := NewChecker(WithPrivileged(p), WithSharedSocket(true))
+	if err != nil {
+		t.Fatalf("failed to create checker: %s", err)
+	}
+
+	b, err := NewChecker(WithPrivileged(p), WithSharedSocket(true))
+	if err != nil {
+		t.Fatalf("failed to create checker: %s", err)
+	}
+
+	if a.engine != b.engine {
+		t.Fatalf("expected checkers to share an engine")
+	}
+}
+
+func TestEnginePing(t *testing.T) {
+	e, err := sharedEngine(privileged(t))
+	if err != nil {
+		t.Fatalf("failed to open engine: %s", err)
+	}
+
+	stats := e.ping(_loopback, 3, 10*time.Millisecond, 2*time.Second)
+	if stats.PacketsSent != 3 || stats.PacketsRecv != 3 {
+		t.Fatalf("sent %d and received %d pings, want 3 and 3", stats.PacketsSent, stats.PacketsRecv)
+	}
+
+	if stats.PacketLoss != 0 {
+		t.Errorf("got packet loss %f, want 0", stats.PacketLoss)
+	}
+
+	if stats.MinRtt <= 0 || stats.MinRtt > stats.AvgRtt || stats.AvgRtt > stats.MaxRtt {
+		t.Errorf("inconsistent rtts: min %s, avg %s, max %s", stats.MinRtt, stats.AvgRtt, stats.MaxRtt)
+	}
+
+	e.mu.Lock()
+	pending := len(e.pending)
+	e.mu.Unlock()
+	if pending != 0 {
+		t.Errorf("expected no pending requests, got %d", pending)
+	}
+}
+
+func TestEnginePingParallel(t *testing.T) {
+	e, err := sharedEngine(privileged(t))
+	if err != nil {
+		t.Fatalf("failed to open engine: %s", err)
+	}
+
+	const n = 200
+	lost := int32(0)
+	done := make(chan struct{})
+	for i := 0; i < n; i++ {
+		go func() {
+			stats := e.ping(_loopback, 2, 10*time.Millisecond, 5*time.Second)
+			atomic.AddInt32(&lost, int32(stats.PacketsSent-stats.PacketsRecv))
+			done <- struct{}{}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		<-done
+	}
+
+	if lost != 0 {
+		t.Errorf("lost %d pings", lost)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	stats := summarize(&ping.Statistics{
+		PacketsSent: 4,
+		PacketsRecv: 3,
+		Rtts:        []time.Duration{2 * time.Millisecond, 4 * time.Millisecond, 6 * time.Millisecond},
+	})
+
+	if stats.PacketLoss != 25 {
+		t.Errorf("got packet loss %f, want 25", stats.PacketLoss)
+	}
+
+	if stats.MinRtt != 2*time.Millisecond || stats.MaxRtt != 6*time.Millisecond || stats.AvgRtt != 4*time.Millisecond {
+		t.Errorf("got min/avg/max %s/%s/%s, want 2ms/4ms/6ms", stats.MinRtt, stats.AvgRtt, stats.MaxRtt)
+	}
+
+	// sqrt((4 + 0 + 4) / 3) ms
+	if want := 1632993 * time.Nanosecond; stats.StdDevRtt < want-time.Microsecond || stats.StdDevRtt > want+time.Microsecond {
+		t.Errorf("got stddev %s, want about %s", stats.StdDevRtt, want)
+	}
+}
+
+// BenchmarkEngine pings loopback through the shared engine from many
+// goroutines at once
+func BenchmarkEngine(b *testing.B) {
+	e, err := sharedEngine(privileged(b))
+	if err != nil {
+		b.Fatalf("failed to open engine: %s", err)
+	}
+
+	lost := int64(0)
+	b.SetParallelism(benchmarkParallelism)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			stats := e.ping(_loopback, 1, time.Second, time.Second)
+			atomic.AddInt64(&lost, int64(stats.PacketsSent-stats.PacketsRecv))
+		}
+	})
+
+	b.ReportMetric(float64(lost)/float64(b.N), "lost/op")
+}
+
+// BenchmarkPinger pings loopback with a new go-ping Pinger, and so a new
+// socket, for every ping, as checkers without a shared socket do
+func BenchmarkPinger(b *testing.B) {
+	p := privileged(b)
+
+	lost := int64(0)
+	b.SetParallelism(benchmarkParallelism)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			pinger, err := ping.NewPinger(_loopback.String())
+			if err != nil {
+				b.Errorf("failed to create pinger: %s", err)
+				return
+			}
+
+			pinger.SetPrivileged(p)
+			pinger.Count = 1
+			pinger.Interval = time.Second
+			pinger.Timeout = time.Second
+			pinger.Run()
+
+			stats := pinger.Statistics()
+			atomic.AddInt64(&lost, int64(stats.PacketsSent-stats.PacketsRecv))
+		}
+	})
+
+	b.ReportMetric(float64(lost)/float64(b.N), "lost/op")
+}
@@ -27,3 +27,49 @@ func WithCount(i int) Option {
 		c.numPings = i
 	}
 }
+
+// WithPrivileged allows the user to choose between raw ICMP sockets, which
+// require root or CAP_NET_RAW, and unprivileged UDP ICMP sockets. By default
+// raw sockets are used if they are permitted
+func WithPrivileged(p bool) Option {
+	return func(c *Checker) {
+		c.privileged = &p
+	}
+}
+
+// WithMaxLoss allows the user to set the percentage of pings which may be lost
+// before the check fails. The default is 34, so that losing 1 of the default
+// 3 pings, as a busy wireless device often does, still passes
+func WithMaxLoss(percent float64) Option {
+	return func(c *Checker) {
+		c.maxLoss = percent
+	}
+}
+
+// WithMaxRTT allows the user to set the highest average round trip time (in
+// milliseconds) before the check fails. 0, the default, disables the limit
+func WithMaxRTT(ms int) Option {
+	return func(c *Checker) {
+		c.maxRTT = ms
+	}
+}
+
+// WithMaxJitter allows the user to set the highest standard deviation of the
+// round trip times (in milliseconds) before the check fails. 0, the default,
+// disables the limit
+func WithMaxJitter(ms int) Option {
+	return func(c *Checker) {
+		c.maxJitter = ms
+	}
+}
+
+// WithSharedSocket allows the user to send the pings of every check through
+// a single ICMP socket rather than opening a socket for each check. The
+// socket is shared by every checker in the process with the same privilege
+// mode. This is recommended when monitoring many devices. IPv6 devices still
+// use their own sockets
+func WithSharedSocket(shared bool) Option {
+	return func(c *Checker) {
+		c.sharedSocket = shared
+	}
+}
@@ -2,6 +2,8 @@ package ping
 
 import (
 	"fmt"
+	"net"
+	"strings"
 	"time"
 
 	"github.com/byuoitav/barrelman"
@@ -9,12 +11,29 @@ import (
 	"github.com/go-ping/ping"
 )
 
-// Checker attempts to ping the device to check for network layer health
+// ConfigKey is the name of the device CheckerConfig read by the checker.
+// The count, pingInterval, timeout, maxLoss, maxRTT, and maxJitter options
+// override the checker's own settings for the device
+const ConfigKey = "ping"
+
+// Checker attempts to ping the device to check for network layer health.
+// Pings are sent over raw sockets when permitted, falling back to
+// unprivileged UDP ICMP sockets otherwise
 type Checker struct {
 	numPings int
 	interval int
 	timeout  int
 
+	maxLoss   float64
+	maxRTT    int
+	maxJitter int
+
+	// privileged is nil until it is either set or detected
+	privileged *bool
+
+	sharedSocket bool
+	engine       *engine
+
 	messenger *messenger.Messenger
 }
 
@@ -24,6 +43,7 @@ func NewChecker(opts ...Option) (*Checker, error) {
 		numPings: 3,
 		interval: 1,
 		timeout:  5,
+		maxLoss:  34,
 	}
 
 	// Apply options
@@ -31,12 +51,34 @@ func NewChecker(opts ...Option) (*Checker, error) {
 		opt(&c)
 	}
 
+	if c.privileged == nil {
+		privileged, err := detectPrivileged()
+		if err != nil {
+			return nil, err
+		}
+
+		c.privileged = &privileged
+	} else if err := canListen(*c.privileged); err != nil {
+		return nil, fmt.Errorf("Unable to open an ICMP socket (privileged: %t): %w", *c.privileged, err)
+	}
+
+	if c.sharedSocket {
+		e, err := sharedEngine(*c.privileged)
+		if err != nil {
+			return nil, err
+		}
+
+		c.engine = e
+	}
+
 	return &c, nil
 }
 
-// Check attempts to ping the given device. If all pings return successfully
-// then the check is considered healthy. Any missed packets will result in an
-// unhealthy check
+// Check attempts to ping the given device. If the packet loss, average round
+// trip time, and jitter are all within their limits then the check is
+// considered healthy. The device is only reported offline if every ping was
+// lost. The round trip times (in milliseconds) and packet loss (as a
+// percentage) are returned as metrics
 func (c *Checker) Check(d *barrelman.Device, forceRecheck bool) barrelman.CheckResult {
 	result := barrelman.CheckResult{
 		RunTime: time.Now(),
@@ -48,33 +90,116 @@ func (c *Checker) Check(d *barrelman.Device, forceRecheck bool) barrelman.CheckR
 		},
 	}
 
-	pinger, err := ping.NewPinger(d.Address)
+	// Apply any device specific settings
+	numPings, interval, timeout := c.numPings, c.interval, c.timeout
+	maxLoss, maxRTT, maxJitter := c.maxLoss, c.maxRTT, c.maxJitter
+	conf := d.Config(ConfigKey)
+	if i, ok := conf.Int("count"); ok && i > 0 {
+		numPings = i
+	}
+	if i, ok := conf.Int("pingInterval"); ok && i > 0 {
+		interval = i
+	}
+	if i, ok := conf.Int("timeout"); ok && i > 0 {
+		timeout = i
+	}
+	if f, ok := conf.Float("maxLoss"); ok {
+		maxLoss = f
+	}
+	if i, ok := conf.Int("maxRTT"); ok {
+		maxRTT = i
+	}
+	if i, ok := conf.Int("maxJitter"); ok {
+		maxJitter = i
+	}
+
+	stats, err := c.ping(d.Address, numPings, time.Duration(interval)*time.Second, time.Duration(timeout)*time.Second)
 	if err != nil {
 		result.Passed = false
-		result.Error = fmt.Sprintf("Failed to creating pinger: %s", err)
+		result.Error = err.Error()
 		result.Event.Value = "Offline"
 		return result
 	}
 
-	pinger.SetPrivileged(true)
-	pinger.Count = c.numPings
-	pinger.Interval = time.Duration(c.interval) * time.Second
-	pinger.Timeout = time.Duration(c.timeout) * time.Second
+	// Pings that weren't sent before the timeout are counted as lost
+	lost := numPings - stats.PacketsRecv
+	loss := float64(lost) / float64(numPings) * 100
 
-	pinger.Run()
-
-	stats := pinger.Statistics()
+	result.Metrics = map[string]barrelman.Metric{
+		"packet-loss": {Value: loss, Unit: "%"},
+	}
 
-	if stats.PacketLoss == 0 {
-		result.Message = fmt.Sprintf(
-			"All pings returned successfully with average RTT of %fms",
-			float64(stats.AvgRtt/time.Nanosecond)/1000000, // Getting ms down to several decimal places
-		)
+	if stats.PacketsRecv == 0 {
+		result.Passed = false
+		result.Error = fmt.Sprintf("Lost all %d pings", numPings)
+		result.Event.Value = "Offline"
 		return result
 	}
 
-	result.Error = fmt.Sprintf("Lost %d of %d pings", c.numPings-stats.PacketsRecv, c.numPings)
-	result.Passed = false
-	result.Event.Value = "Offline"
+	result.Metrics["rtt-min"] = barrelman.Metric{Value: ms(stats.MinRtt), Unit: "ms"}
+	result.Metrics["rtt-avg"] = barrelman.Metric{Value: ms(stats.AvgRtt), Unit: "ms"}
+	result.Metrics["rtt-max"] = barrelman.Metric{Value: ms(stats.MaxRtt), Unit: "ms"}
+	result.Metrics["rtt-stddev"] = barrelman.Metric{Value: ms(stats.StdDevRtt), Unit: "ms"}
+
+	result.Message = fmt.Sprintf(
+		"%d of %d pings returned with RTT min/avg/max/stddev of %f/%f/%f/%fms",
+		stats.PacketsRecv,
+		numPings,
+		ms(stats.MinRtt),
+		ms(stats.AvgRtt),
+		ms(stats.MaxRtt),
+		ms(stats.StdDevRtt),
+	)
+
+	problems := []string{}
+	if loss > maxLoss {
+		problems = append(problems, fmt.Sprintf("lost %d of %d pings", lost, numPings))
+	}
+	if maxRTT > 0 && stats.AvgRtt > time.Duration(maxRTT)*time.Millisecond {
+		problems = append(problems, fmt.Sprintf("average RTT over %dms", maxRTT))
+	}
+	if maxJitter > 0 && stats.StdDevRtt > time.Duration(maxJitter)*time.Millisecond {
+		problems = append(problems, fmt.Sprintf("jitter over %dms", maxJitter))
+	}
+
+	if len(problems) > 0 {
+		result.Passed = false
+		result.Error = "Connection is unstable: " + strings.Join(problems, ", ")
+	}
+
 	return result
 }
+
+// ping pings the address through the shared engine if there is one and the
+// address is IPv4, and with its own pinger otherwise
+func (c *Checker) ping(address string, count int, interval, timeout time.Duration) (*ping.Statistics, error) {
+	if c.engine != nil {
+		addr, err := net.ResolveIPAddr("ip", address)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to resolve address: %w", err)
+		}
+
+		if ip := addr.IP.To4(); ip != nil {
+			return c.engine.ping(ip, count, interval, timeout), nil
+		}
+	}
+
+	pinger, err := ping.NewPinger(address)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create pinger: %w", err)
+	}
+
+	pinger.SetPrivileged(*c.privileged)
+	pinger.Count = count
+	pinger.Interval = interval
+	pinger.Timeout = timeout
+
+	pinger.Run()
+
+	return pinger.Statistics(), nil
+}
+
+// ms converts d to milliseconds, down to several decimal places
+func ms(d time.Duration) float64 {
+	return float64(d/time.Nanosecond) / 1000000
+}
@@ -0,0 +1,41 @@
+package ping
+
+import (
+	"fmt"
+
+	"golang.org/x/net/icmp"
+)
+
+// canListen returns nil if an ICMP socket can be opened in the given mode.
+// Privileged mode uses raw sockets, which require root or CAP_NET_RAW.
+// Unprivileged mode uses UDP ICMP sockets, which on Linux require the
+// process's group to be within net.ipv4.ping_group_range
+func canListen(privileged bool) error {
+	network := "udp4"
+	if privileged {
+		network = "ip4:icmp"
+	}
+
+	conn, err := icmp.ListenPacket(network, "0.0.0.0")
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
+
+// detectPrivileged returns whether raw sockets should be used, preferring
+// them when they are permitted and falling back to unprivileged sockets
+func detectPrivileged() (bool, error) {
+	rawErr := canListen(true)
+	if rawErr == nil {
+		return true, nil
+	}
+
+	udpErr := canListen(false)
+	if udpErr == nil {
+		return false, nil
+	}
+
+	return false, fmt.Errorf("Unable to open an ICMP socket (raw: %s, unprivileged: %s). Run with CAP_NET_RAW or include the process's group in net.ipv4.ping_group_range", rawErr, udpErr)
+}
@@ -0,0 +1,45 @@
+package pjlink
+
+// Option is a function which modifies a given checker, allowing the
+// user to have an option on how to setup the checker
+type Option func(*Checker)
+
+// WithPort allows the user to set the port PJLink commands are sent to. The
+// default is 4352
+func WithPort(p int) Option {
+	return func(c *Checker) {
+		c.port = p
+	}
+}
+
+// WithTimeout allows the user to set the timeout (in seconds) of the
+// connection to the device. The default is 5 seconds
+func WithTimeout(t int) Option {
+	return func(c *Checker) {
+		c.timeout = t
+	}
+}
+
+// WithPassword allows the user to set the password used to authenticate with
+// devices that have PJLink security enabled
+func WithPassword(password string) Option {
+	return func(c *Checker) {
+		c.password = password
+	}
+}
+
+// WithMaxLampHours allows the user to set the number of hours a lamp may be
+// used before the check fails. 0, the default, disables the limit
+func WithMaxLampHours(hours int) Option {
+	return func(c *Checker) {
+		c.maxLampHours = hours
+	}
+}
+
+// WithFailOnWarning allows the user to fail the check when the device
+// reports a warning, not just an error
+func WithFailOnWarning(fail bool) Option {
+	return func(c *Checker) {
+		c.failOnWarning = fail
+	}
+}
@@ -0,0 +1,202 @@
+package pjlink
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/byuoitav/barrelman"
+)
+
+// ConfigKey is the name of the device CheckerConfig read by the checker.
+// The port, timeout, password, maxLampHours, and failOnWarning options
+// override the checker's own settings for the device
+const ConfigKey = "pjlink"
+
+// _errorFlags are the names of the six ERST status flags, in order
+var _errorFlags = []string{"fan", "lamp", "temperature", "cover", "filter", "other"}
+
+// _powerStates maps POWR values to event values
+var _powerStates = map[string]string{
+	"0": "Off",
+	"1": "On",
+	"2": "Cooling",
+	"3": "Warming",
+}
+
+// _flagStates maps ERST flag values to event values
+var _flagStates = map[byte]string{
+	'0': "Ok",
+	'1': "Warning",
+	'2': "Error",
+}
+
+// Checker queries a projector or display over PJLink for its power, input,
+// lamp, and error status
+type Checker struct {
+	port          int
+	timeout       int
+	password      string
+	maxLampHours  int
+	failOnWarning bool
+}
+
+// NewChecker returns a pjlink checker with the given options set
+func NewChecker(opts ...Option) (*Checker, error) {
+	c := Checker{
+		port:    4352,
+		timeout: 5,
+	}
+
+	// Apply options
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return &c, nil
+}
+
+// Check queries the device's status. If the device reports no errors and its
+// lamps are within their rated hours then the check is considered healthy.
+// The power state, input, lamp hours, and each error flag are emitted as
+// events
+func (c *Checker) Check(d *barrelman.Device, forceRecheck bool) barrelman.CheckResult {
+	result := barrelman.CheckResult{
+		RunTime: time.Now(),
+		Passed:  true,
+		Event: barrelman.Event{
+			Device: d,
+			Key:    "pjlink",
+			Value:  "Ok",
+		},
+	}
+
+	fail := func(value, format string, a ...interface{}) barrelman.CheckResult {
+		result.Passed = false
+		result.Error = fmt.Sprintf(format, a...)
+		result.Event.Value = value
+		return result
+	}
+
+	event := func(key, value string) {
+		result.Events = append(result.Events, barrelman.Event{Device: d, Key: key, Value: value})
+	}
+
+	// Apply any device specific settings
+	port, timeout, password, maxLampHours, failOnWarning := c.port, c.timeout, c.password, c.maxLampHours, c.failOnWarning
+	conf := d.Config(ConfigKey)
+	if i, ok := conf.Int("port"); ok && i > 0 {
+		port = i
+	}
+	if i, ok := conf.Int("timeout"); ok && i > 0 {
+		timeout = i
+	}
+	if s, ok := conf.String("password"); ok {
+		password = s
+	}
+	if i, ok := conf.Int("maxLampHours"); ok {
+		maxLampHours = i
+	}
+	if b, ok := conf.Bool("failOnWarning"); ok {
+		failOnWarning = b
+	}
+
+	conn, err := dial(net.JoinHostPort(d.Address, strconv.Itoa(port)), password, time.Duration(timeout)*time.Second)
+	if err != nil {
+		return fail("Unreachable", "Failed to connect: %s", err)
+	}
+	defer conn.Close()
+
+	status := []string{}
+	problems := []string{}
+
+	power, err := conn.query("%1POWR")
+	if err != nil {
+		return fail("Failed", "Failed to get power state: %s", err)
+	}
+
+	if p, ok := _powerStates[power]; ok {
+		power = p
+	}
+	status = append(status, "Power "+strings.ToLower(power))
+	event("power", power)
+
+	// Input can't be read while the device is off on some models
+	if input, err := conn.query("%1INPT"); err == nil {
+		status = append(status, "input "+input)
+		event("input", input)
+	}
+
+	lamps, err := conn.query("%1LAMP")
+	switch {
+	case errors.Is(err, errUnsupported):
+		// Displays have no lamp
+	case err != nil:
+		return fail("Failed", "Failed to get lamp status: %s", err)
+	default:
+		// Pairs of "hours on/off" for each lamp
+		fields := strings.Fields(lamps)
+		for i := 0; i+1 < len(fields); i += 2 {
+			num := i/2 + 1
+			key := "lamp-hours"
+			if num > 1 {
+				key = fmt.Sprintf("lamp-hours-%d", num)
+			}
+
+			status = append(status, fmt.Sprintf("lamp %d at %s hours", num, fields[i]))
+			event(key, fields[i])
+
+			hours, err := strconv.Atoi(fields[i])
+			if err != nil {
+				continue
+			}
+
+			if result.Metrics == nil {
+				result.Metrics = make(map[string]barrelman.Metric)
+			}
+			result.Metrics[key] = barrelman.Metric{Value: float64(hours), Unit: "hours"}
+
+			if maxLampHours > 0 && hours >= maxLampHours {
+				problems = append(problems, fmt.Sprintf("Lamp %d has been used %d of %d hours", num, hours, maxLampHours))
+			}
+		}
+	}
+
+	flags, err := conn.query("%1ERST")
+	if err != nil {
+		return fail("Failed", "Failed to get error status: %s", err)
+	}
+
+	if len(flags) != len(_errorFlags) {
+		return fail("Failed", "Invalid error status %q", flags)
+	}
+
+	if result.Metrics == nil {
+		result.Metrics = make(map[string]barrelman.Metric)
+	}
+	result.Metrics["warnings"] = barrelman.Metric{Value: float64(strings.Count(flags, "1"))}
+	result.Metrics["errors"] = barrelman.Metric{Value: float64(strings.Count(flags, "2"))}
+
+	for i, name := range _errorFlags {
+		state, ok := _flagStates[flags[i]]
+		if !ok {
+			state = "Unknown"
+		}
+
+		event("error-"+name, state)
+		if flags[i] == '2' || (failOnWarning && flags[i] == '1') {
+			problems = append(problems, fmt.Sprintf("Device reported %s %s", name, strings.ToLower(state)))
+		}
+	}
+
+	result.Message = strings.Join(status, ", ")
+
+	if len(problems) > 0 {
+		return fail("Failed", "%s", strings.Join(problems, "; "))
+	}
+
+	return result
+}
@@ -0,0 +1,109 @@
+package pjlink
+
+import (
+	"bufio"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// errUnsupported is returned when the device doesn't support a command (ERR1)
+var errUnsupported = errors.New("command not supported")
+
+// conn is an authenticated PJLink session with a device
+type conn struct {
+	conn net.Conn
+	r    *bufio.Reader
+
+	// digest is prefixed to the first command when authentication is needed
+	digest string
+}
+
+// dial connects to the device and reads its greeting, preparing to
+// authenticate the first command if the device requires it
+func dial(addr, password string, timeout time.Duration) (*conn, error) {
+	nc, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	nc.SetDeadline(time.Now().Add(timeout))
+	c := &conn{conn: nc, r: bufio.NewReader(nc)}
+
+	greeting, err := c.readLine()
+	if err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("Failed to read greeting: %w", err)
+	}
+
+	fields := strings.Fields(greeting)
+	switch {
+	case len(fields) == 2 && fields[0] == "PJLINK" && fields[1] == "0":
+	case len(fields) == 3 && fields[0] == "PJLINK" && fields[1] == "1":
+		if password == "" {
+			nc.Close()
+			return nil, fmt.Errorf("device requires a password")
+		}
+
+		sum := md5.Sum([]byte(fields[2] + password))
+		c.digest = hex.EncodeToString(sum[:])
+	default:
+		nc.Close()
+		return nil, fmt.Errorf("unexpected greeting %q", greeting)
+	}
+
+	return c, nil
+}
+
+// query sends a get command (e.g. "%1POWR") and returns the device's answer
+func (c *conn) query(cmd string) (string, error) {
+	if _, err := fmt.Fprintf(c.conn, "%s%s ?\r", c.digest, cmd); err != nil {
+		return "", err
+	}
+	c.digest = ""
+
+	resp, err := c.readLine()
+	if err != nil {
+		return "", err
+	}
+
+	if resp == "PJLINK ERRA" {
+		return "", fmt.Errorf("authentication failed")
+	}
+
+	prefix := cmd + "="
+	if !strings.HasPrefix(resp, prefix) {
+		return "", fmt.Errorf("unexpected response %q", resp)
+	}
+
+	value := strings.TrimPrefix(resp, prefix)
+	switch value {
+	case "ERR1":
+		return "", errUnsupported
+	case "ERR2":
+		return "", fmt.Errorf("out of parameter")
+	case "ERR3":
+		return "", fmt.Errorf("unavailable time")
+	case "ERR4":
+		return "", fmt.Errorf("projector/display failure")
+	}
+
+	return value, nil
+}
+
+func (c *conn) readLine() (string, error) {
+	line, err := c.r.ReadString('\r')
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(line), nil
+}
+
+func (c *conn) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,48 @@
+package script
+
+// Option is a function which modifies a given checker, allowing the
+// user to have an option on how to setup the checker
+type Option func(*Checker)
+
+// WithCommand allows the user to add a command, under the given name, that
+// devices may choose to run through their CheckerConfig. It sets the
+// executable run and the arguments it is passed by default. Arguments may
+// include {name}, {address}, and {room}, which are replaced with the device's
+// values. Only commands added this way can be run
+func WithCommand(name, path string, args ...string) Option {
+	return func(c *Checker) {
+		c.commands[name] = command{path: path, args: args}
+	}
+}
+
+// WithDefaultCommand allows the user to set the name of the command run on
+// devices that don't choose one. By default nothing is run on those devices
+func WithDefaultCommand(name string) Option {
+	return func(c *Checker) {
+		c.defaultCommand = name
+	}
+}
+
+// WithTimeout allows the user to set the timeout (in seconds) after which
+// the command is killed and the check fails. The default is 30 seconds
+func WithTimeout(t int) Option {
+	return func(c *Checker) {
+		c.timeout = t
+	}
+}
+
+// WithPassingCodes allows the user to set the exit codes which are
+// considered passing. The default is 0
+func WithPassingCodes(codes ...int) Option {
+	return func(c *Checker) {
+		c.passingCodes = codes
+	}
+}
+
+// WithEnv allows the user to set additional environment variables, in the
+// form key=value, that the command is run with
+func WithEnv(env ...string) Option {
+	return func(c *Checker) {
+		c.env = env
+	}
+}
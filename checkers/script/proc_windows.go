@@ -0,0 +1,11 @@
+package script
+
+import "os/exec"
+
+// setProcessGroup does nothing, since windows has no process groups
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kills the command. Processes it started are left running
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
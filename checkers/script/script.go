@@ -0,0 +1,269 @@
+package script
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/byuoitav/barrelman"
+)
+
+// ConfigKey is the name of the device CheckerConfig read by the checker.
+// The command option chooses which of the checker's commands is run, by name,
+// and the args, timeout, and passingCodes options override the checker's own
+// settings for the device. Devices can't run commands the checker wasn't
+// given, since anyone who can edit a device could otherwise run anything on
+// the monitor
+const ConfigKey = "script"
+
+// Checker runs an external command to check the device, so that checks can
+// be added without recompiling. The device's name, address, room, and tags
+// are passed to the command in the BARRELMAN_DEVICE_NAME,
+// BARRELMAN_DEVICE_ADDRESS, BARRELMAN_DEVICE_ROOM, and BARRELMAN_DEVICE_TAGS
+// environment variables.
+//
+// The command's exit code decides whether the check passed, and the first
+// line of its output is used as the message. Alternatively the command may
+// print a JSON object with any of the fields passed, message, error, key,
+// value, and metrics, which take precedence over the exit code and plain
+// output. Metrics map names to objects with a value and an optional unit
+type Checker struct {
+	commands       map[string]command
+	defaultCommand string
+	timeout        int
+	passingCodes   []int
+	env            []string
+}
+
+// command is an executable the checker is allowed to run
+type command struct {
+	path string
+	args []string
+}
+
+// output is the JSON a command may print to describe its result
+type output struct {
+	Passed  *bool  `json:"passed"`
+	Message string `json:"message"`
+	Error   string `json:"error"`
+	Key     string `json:"key"`
+	Value   string `json:"value"`
+
+	Metrics map[string]struct {
+		Value float64 `json:"value"`
+		Unit  string  `json:"unit"`
+	} `json:"metrics"`
+}
+
+// NewChecker returns a script checker with the given options set
+func NewChecker(opts ...Option) (*Checker, error) {
+	c := Checker{
+		commands:     make(map[string]command),
+		timeout:      30,
+		passingCodes: []int{0},
+	}
+
+	// Apply options
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	for name, cmd := range c.commands {
+		if _, err := exec.LookPath(cmd.path); err != nil {
+			return nil, fmt.Errorf("Unable to find command %s: %w", name, err)
+		}
+	}
+
+	if _, ok := c.commands[c.defaultCommand]; c.defaultCommand != "" && !ok {
+		return nil, fmt.Errorf("Default command %s was never added", c.defaultCommand)
+	}
+
+	return &c, nil
+}
+
+// Check runs the command for the device. If the command exits with a passing
+// code (or reports that it passed) then the check is considered healthy.
+// Devices with no configured command are skipped
+func (c *Checker) Check(d *barrelman.Device, forceRecheck bool) barrelman.CheckResult {
+	result := barrelman.CheckResult{
+		RunTime: time.Now(),
+		Passed:  true,
+		Event: barrelman.Event{
+			Device: d,
+			Key:    "script",
+			Value:  "Ok",
+		},
+	}
+
+	// Apply any device specific settings
+	name, timeout, passingCodes := c.defaultCommand, c.timeout, c.passingCodes
+	conf := d.Config(ConfigKey)
+	if s, ok := conf.String("command"); ok {
+		name = s
+	}
+	if i, ok := conf.Int("timeout"); ok && i > 0 {
+		timeout = i
+	}
+	if codes, ok := conf.Ints("passingCodes"); ok {
+		passingCodes = codes
+	}
+
+	if name == "" {
+		return barrelman.CheckResult{
+			RunTime: time.Now(),
+			Skipped: true,
+			Message: "No command to run",
+		}
+	}
+
+	cmdConf, ok := c.commands[name]
+	if !ok {
+		result.Passed = false
+		result.Error = fmt.Sprintf("Invalid config: unknown command %q", name)
+		result.Event.Value = "Failed"
+		return result
+	}
+
+	args := cmdConf.args
+	if a, ok := conf.Strings("args"); ok {
+		args = a
+	}
+
+	replacer := strings.NewReplacer("{name}", d.Name, "{address}", d.Address, "{room}", d.Room)
+	expanded := make([]string, len(args))
+	for i, arg := range args {
+		expanded[i] = replacer.Replace(arg)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(cmdConf.path, expanded...)
+	setProcessGroup(cmd)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Env = append(os.Environ(), c.env...)
+	cmd.Env = append(cmd.Env,
+		"BARRELMAN_DEVICE_NAME="+d.Name,
+		"BARRELMAN_DEVICE_ADDRESS="+d.Address,
+		"BARRELMAN_DEVICE_ROOM="+d.Room,
+		"BARRELMAN_DEVICE_TAGS="+strings.Join(d.Tags, ","),
+	)
+
+	start := time.Now()
+	err := run(ctx, cmd)
+	runTime := barrelman.Metric{Value: float64(time.Since(start)/time.Nanosecond) / 1000000, Unit: "ms"}
+
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		result.Passed = false
+		result.Error = fmt.Sprintf("Timed out after %d seconds", timeout)
+		result.Event.Value = "Failed"
+		return result
+	case errors.As(err, &exitErr):
+		result.Passed = contains(passingCodes, exitErr.ExitCode())
+	case err != nil:
+		result.Passed = false
+		result.Error = fmt.Sprintf("Failed to run command: %s", err)
+		result.Event.Value = "Failed"
+		return result
+	default:
+		result.Passed = contains(passingCodes, 0)
+	}
+
+	var out output
+	if trimmed := bytes.TrimSpace(stdout.Bytes()); bytes.HasPrefix(trimmed, []byte("{")) && json.Unmarshal(trimmed, &out) == nil {
+		if out.Passed != nil {
+			result.Passed = *out.Passed
+		}
+
+		result.Message = out.Message
+		result.Error = out.Error
+		if out.Key != "" {
+			result.Event.Key = out.Key
+		}
+		if out.Value != "" {
+			result.Event.Value = out.Value
+		}
+
+		if len(out.Metrics) > 0 {
+			result.Metrics = make(map[string]barrelman.Metric, len(out.Metrics))
+			for name, m := range out.Metrics {
+				result.Metrics[name] = barrelman.Metric{Value: m.Value, Unit: m.Unit}
+			}
+		}
+	} else {
+		result.Message = firstLine(stdout.String())
+	}
+
+	if result.Metrics == nil {
+		result.Metrics = make(map[string]barrelman.Metric)
+	}
+	result.Metrics["run-time"] = runTime
+	result.Metrics["exit-code"] = barrelman.Metric{Value: float64(cmd.ProcessState.ExitCode())}
+
+	if !result.Passed {
+		if result.Error == "" {
+			result.Error = firstLine(stderr.String())
+		}
+		if result.Error == "" {
+			result.Error = fmt.Sprintf("Exited with code %d", cmd.ProcessState.ExitCode())
+		}
+		if out.Value == "" {
+			result.Event.Value = "Failed"
+		}
+	}
+
+	return result
+}
+
+// run starts the command and waits for it to exit. When the context is done
+// the command's whole process group is killed, so that any children holding
+// its output open don't keep it running past the timeout
+func run(ctx context.Context, cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			killProcessGroup(cmd)
+		case <-done:
+		}
+	}()
+
+	return cmd.Wait()
+}
+
+// firstLine returns the first non-empty line of s
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+
+	return ""
+}
+
+func contains(codes []int, code int) bool {
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+
+	return false
+}
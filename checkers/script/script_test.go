@@ -0,0 +1,133 @@
+package script
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/byuoitav/barrelman"
+)
+
+func testDevice(conf barrelman.CheckerConfig) *barrelman.Device {
+	d := &barrelman.Device{
+		Name:    "ITB-1101-CP1",
+		Address: "127.0.0.1",
+		Room:    "ITB-1101",
+	}
+
+	if conf != nil {
+		d.CheckerConfig = map[string]barrelman.CheckerConfig{ConfigKey: conf}
+	}
+
+	return d
+}
+
+func newTestChecker(t *testing.T, opts ...Option) *Checker {
+	t.Helper()
+
+	c, err := NewChecker(opts...)
+	if err != nil {
+		t.Fatalf("failed to create checker: %s", err)
+	}
+
+	return c
+}
+
+func TestCheckCommands(t *testing.T) {
+	c := newTestChecker(t,
+		WithCommand("echo", "sh", "-c", `echo "checked $0"`, "{name}"),
+		WithCommand("fail", "sh", "-c", "echo broken >&2; exit 2"),
+		WithDefaultCommand("echo"),
+	)
+
+	tests := []struct {
+		name    string
+		conf    barrelman.CheckerConfig
+		passed  bool
+		message string
+		err     string
+	}{
+		{
+			name:    "default command",
+			passed:  true,
+			message: "checked ITB-1101-CP1",
+		},
+		{
+			name:    "device args",
+			conf:    barrelman.CheckerConfig{"args": []interface{}{"-c", `echo "at $0"`, "{address}"}},
+			passed:  true,
+			message: "at 127.0.0.1",
+		},
+		{
+			name: "device command",
+			conf: barrelman.CheckerConfig{"command": "fail"},
+			err:  "broken",
+		},
+		{
+			name:    "device passing codes",
+			conf:    barrelman.CheckerConfig{"command": "fail", "passingCodes": []interface{}{0, 2}},
+			passed:  true,
+			message: "",
+		},
+		{
+			name: "command not added",
+			conf: barrelman.CheckerConfig{"command": "/bin/rm"},
+			err:  `Invalid config: unknown command "/bin/rm"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := c.Check(testDevice(tt.conf), false)
+			if result.Passed != tt.passed {
+				t.Fatalf("got passed %t, want %t (error %q)", result.Passed, tt.passed, result.Error)
+			}
+
+			if result.Message != tt.message {
+				t.Errorf("got message %q, want %q", result.Message, tt.message)
+			}
+
+			if result.Error != tt.err {
+				t.Errorf("got error %q, want %q", result.Error, tt.err)
+			}
+		})
+	}
+}
+
+func TestCheckNoCommand(t *testing.T) {
+	c := newTestChecker(t, WithCommand("echo", "echo", "ok"))
+
+	result := c.Check(testDevice(nil), false)
+	if result.Passed || !result.Skipped || result.Message != "No command to run" || result.Event.Key != "" {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
+
+func TestCheckTimeoutKillsChildren(t *testing.T) {
+	// The backgrounded sleep holds stdout open after the shell is killed
+	c := newTestChecker(t,
+		WithCommand("hang", "sh", "-c", "sleep 30 & sleep 30"),
+		WithDefaultCommand("hang"),
+		WithTimeout(1),
+	)
+
+	start := time.Now()
+	result := c.Check(testDevice(nil), false)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("check took %s, expected about the 1s timeout", elapsed)
+	}
+
+	if result.Passed || !strings.HasPrefix(result.Error, "Timed out") {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
+
+func TestNewCheckerInvalid(t *testing.T) {
+	if _, err := NewChecker(WithCommand("missing", "/nonexistent/check")); err == nil {
+		t.Errorf("expected an error for a missing executable")
+	}
+
+	if _, err := NewChecker(WithDefaultCommand("missing")); err == nil {
+		t.Errorf("expected an error for a default command that wasn't added")
+	}
+}
@@ -0,0 +1,89 @@
+package serial
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// lookupMAC finds the MAC address of the given address in the ARP table. A
+// packet is sent to the address first so that the table has an entry for it.
+// This only works for devices on the same subnet as the monitor
+func (c *Checker) lookupMAC(address string, timeout time.Duration) (string, error) {
+	ip, err := resolve(address)
+	if err != nil {
+		return "", err
+	}
+
+	// Sending anything to the device makes the kernel ARP for it
+	if conn, err := net.DialTimeout("udp4", net.JoinHostPort(ip.String(), "9"), timeout); err == nil {
+		conn.Write([]byte{0})
+		conn.Close()
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		mac, err := c.readARPTable(ip)
+		if err == nil || time.Now().After(deadline) {
+			return mac, err
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// readARPTable finds the complete entry for the given IP in the ARP table
+func (c *Checker) readARPTable(ip net.IP) (string, error) {
+	f, err := os.Open(c.arpTable)
+	if err != nil {
+		return "", fmt.Errorf("Failed to open ARP table: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // Skip the header
+
+	// Each line is: IP address, HW type, Flags, HW address, Mask, Device
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || !ip.Equal(net.ParseIP(fields[0])) {
+			continue
+		}
+
+		// A flag of 0x0 means the entry is incomplete
+		if fields[2] == "0x0" {
+			break
+		}
+
+		return fields[3], nil
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("Failed to read ARP table: %w", err)
+	}
+
+	return "", fmt.Errorf("No ARP entry for %s, the device may be offline or on another subnet", ip)
+}
+
+// resolve returns the IPv4 address of the given address
+func resolve(address string) (net.IP, error) {
+	if ip := net.ParseIP(address); ip != nil {
+		return ip, nil
+	}
+
+	ips, err := net.LookupIP(address)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to resolve %s: %w", address, err)
+	}
+
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			return ip, nil
+		}
+	}
+
+	return nil, fmt.Errorf("No IPv4 address found for %s", address)
+}
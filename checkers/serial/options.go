@@ -0,0 +1,21 @@
+package serial
+
+// Option is a function which modifies a given checker, allowing the
+// user to have an option on how to setup the checker
+type Option func(*Checker)
+
+// WithTimeout allows the user to set the timeout (in seconds) of each query
+// made to a device. The default is 10 seconds
+func WithTimeout(t int) Option {
+	return func(c *Checker) {
+		c.timeout = t
+	}
+}
+
+// WithARPTable allows the user to set the path of the ARP table that MAC
+// addresses are read from. The default is /proc/net/arp
+func WithARPTable(path string) Option {
+	return func(c *Checker) {
+		c.arpTable = path
+	}
+}
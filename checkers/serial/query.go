@@ -0,0 +1,263 @@
+package serial
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/byuoitav/barrelman"
+	"github.com/gosnmp/gosnmp"
+)
+
+// _maxResponseSize is the most of a response that is searched for a serial
+const _maxResponseSize = 1 << 20
+
+// Telnet command bytes used to refuse option negotiation
+const (
+	_iac  = 255
+	_dont = 254
+	_do   = 253
+	_wont = 252
+	_will = 251
+	_sb   = 250
+	_se   = 240
+)
+
+// _entPhysicalSerialNum is the ENTITY-MIB serial number of the first
+// physical entity, which is the chassis on most devices
+const _entPhysicalSerialNum = "1.3.6.1.2.1.47.1.1.1.1.11.1"
+
+// querySerial queries the serial number of the device using the method in
+// the device's config
+func querySerial(d *barrelman.Device, conf barrelman.CheckerConfig, timeout time.Duration) (string, error) {
+	var re *regexp.Regexp
+	if expr, ok := conf.String("regex"); ok {
+		var err error
+		if re, err = regexp.Compile(expr); err != nil {
+			return "", fmt.Errorf("invalid regex: %w", err)
+		}
+	}
+
+	method, _ := conf.String("method")
+	if re == nil && method != "snmp" {
+		return "", fmt.Errorf("no regex configured")
+	}
+
+	switch method {
+	case "http":
+		return queryHTTP(d, conf, re, timeout)
+	case "telnet":
+		return queryTelnet(d, conf, re, timeout)
+	case "snmp":
+		return querySNMP(d, conf, re, timeout)
+	}
+
+	return "", fmt.Errorf("unknown method %q", method)
+}
+
+// queryHTTP requests the configured url (or path on the device) and searches
+// the response body for the serial number
+func queryHTTP(d *barrelman.Device, conf barrelman.CheckerConfig, re *regexp.Regexp, timeout time.Duration) (string, error) {
+	url, ok := conf.String("url")
+	if !ok {
+		path, _ := conf.String("path")
+		url = fmt.Sprintf("http://%s/%s", d.Address, strings.TrimPrefix(path, "/"))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to make request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return "", fmt.Errorf("got status %d from %s", res.StatusCode, url)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, _maxResponseSize))
+	if err != nil {
+		return "", fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	return match(re, body)
+}
+
+// queryTelnet connects to the device, sends the configured command, and reads
+// the response until the serial number is found or the timeout passes
+func queryTelnet(d *barrelman.Device, conf barrelman.CheckerConfig, re *regexp.Regexp, timeout time.Duration) (string, error) {
+	port := 23
+	if p, ok := conf.Int("port"); ok {
+		port = p
+	}
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(d.Address, strconv.Itoa(port)), timeout)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if cmd, ok := conf.String("command"); ok {
+		if _, err := conn.Write([]byte(cmd + "\r\n")); err != nil {
+			return "", fmt.Errorf("failed to send command: %w", err)
+		}
+	}
+
+	var resp []byte
+	buf := make([]byte, 1024)
+	for len(resp) < _maxResponseSize {
+		n, err := conn.Read(buf)
+		if n > 0 {
+			data, replies := stripTelnet(buf[:n])
+			if len(replies) > 0 {
+				conn.Write(replies)
+			}
+
+			resp = append(resp, data...)
+			if serial, err := match(re, resp); err == nil {
+				return serial, nil
+			}
+		}
+
+		if err != nil {
+			return "", fmt.Errorf("serial not found in response %q: %w", resp, err)
+		}
+	}
+
+	return "", fmt.Errorf("serial not found in response")
+}
+
+// querySNMP gets the configured oid (entPhysicalSerialNum by default) from the
+// device using SNMP v2c. The value is the serial number, unless a regex is
+// configured to extract it from the value
+func querySNMP(d *barrelman.Device, conf barrelman.CheckerConfig, re *regexp.Regexp, timeout time.Duration) (string, error) {
+	oid, ok := conf.String("oid")
+	if !ok {
+		oid = _entPhysicalSerialNum
+	}
+
+	port := 161
+	if p, ok := conf.Int("port"); ok {
+		port = p
+	}
+
+	community, ok := conf.String("community")
+	if !ok {
+		community = "public"
+	}
+
+	client := &gosnmp.GoSNMP{
+		Target:    d.Address,
+		Port:      uint16(port),
+		Community: community,
+		Version:   gosnmp.Version2c,
+		Timeout:   timeout,
+		MaxOids:   gosnmp.MaxOids,
+	}
+
+	if err := client.Connect(); err != nil {
+		return "", fmt.Errorf("failed to connect: %w", err)
+	}
+	defer client.Conn.Close()
+
+	packet, err := client.Get([]string{oid})
+	if err != nil {
+		return "", fmt.Errorf("failed to get %s: %w", oid, err)
+	}
+
+	if packet.Error != gosnmp.NoError {
+		return "", fmt.Errorf("device returned error %s", packet.Error)
+	}
+
+	if len(packet.Variables) != 1 {
+		return "", fmt.Errorf("%s not found", oid)
+	}
+
+	var value []byte
+	switch v := packet.Variables[0].Value.(type) {
+	case []byte:
+		value = v
+	case string:
+		value = []byte(v)
+	default:
+		return "", fmt.Errorf("%s not found", oid)
+	}
+
+	if re != nil {
+		return match(re, value)
+	}
+
+	serial := strings.TrimSpace(string(value))
+	if serial == "" {
+		return "", fmt.Errorf("%s is empty", oid)
+	}
+
+	return serial, nil
+}
+
+// stripTelnet removes telnet commands from the given data, and returns the
+// replies refusing any options the device asked for
+func stripTelnet(b []byte) ([]byte, []byte) {
+	var data, replies bytes.Buffer
+
+	for i := 0; i < len(b); i++ {
+		if b[i] != _iac || i+1 >= len(b) {
+			data.WriteByte(b[i])
+			continue
+		}
+
+		i++
+		switch b[i] {
+		case _iac:
+			data.WriteByte(_iac)
+		case _do, _dont:
+			if i+1 < len(b) {
+				i++
+				replies.Write([]byte{_iac, _wont, b[i]})
+			}
+		case _will, _wont:
+			if i+1 < len(b) {
+				i++
+				replies.Write([]byte{_iac, _dont, b[i]})
+			}
+		case _sb:
+			for i+1 < len(b) && !(b[i] == _iac && b[i+1] == _se) {
+				i++
+			}
+			i++
+		}
+	}
+
+	return data.Bytes(), replies.Bytes()
+}
+
+// match returns the first capture group of the regex in b, or the whole match
+// if it has no capture groups
+func match(re *regexp.Regexp, b []byte) (string, error) {
+	m := re.FindSubmatch(b)
+	switch {
+	case m == nil:
+		return "", fmt.Errorf("serial not found in response")
+	case len(m) > 1:
+		return strings.TrimSpace(string(m[1])), nil
+	}
+
+	return strings.TrimSpace(string(m[0])), nil
+}
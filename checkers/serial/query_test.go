@@ -0,0 +1,92 @@
+package serial
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/byuoitav/barrelman"
+	"github.com/byuoitav/barrelman/internal/snmptest"
+	"github.com/gosnmp/gosnmp"
+)
+
+// newAgent starts a stand-in agent which answers with the given strings
+func newAgent(t *testing.T, values map[string]string) *snmptest.Agent {
+	t.Helper()
+
+	pdus := make(map[string]gosnmp.SnmpPDU, len(values))
+	for oid, v := range values {
+		pdus[oid] = gosnmp.SnmpPDU{Type: gosnmp.OctetString, Value: []byte(v)}
+	}
+
+	a := snmptest.NewAgent(t, pdus)
+	go a.Serve()
+
+	return a
+}
+
+const _testTimeout = 500 * time.Millisecond
+
+func TestQuerySNMP(t *testing.T) {
+	a := newAgent(t, map[string]string{
+		_entPhysicalSerialNum:       "FOC1234X0AB ",
+		"1.3.6.1.4.1.9.3.6.3.0":     "Serial: FOC9876Z1CD",
+		"1.3.6.1.4.1.99999.1.1.1.0": "",
+	})
+
+	d := &barrelman.Device{Name: "ITB-1101-SW1", Address: "127.0.0.1"}
+
+	tests := []struct {
+		name    string
+		conf    barrelman.CheckerConfig
+		want    string
+		wantErr string
+	}{
+		{
+			name: "default oid",
+			conf: barrelman.CheckerConfig{"method": "snmp", "port": a.Port()},
+			want: "FOC1234X0AB",
+		},
+		{
+			name: "oid and regex",
+			conf: barrelman.CheckerConfig{"method": "snmp", "port": a.Port(), "oid": "1.3.6.1.4.1.9.3.6.3.0", "regex": `Serial: (\S+)`},
+			want: "FOC9876Z1CD",
+		},
+		{
+			name:    "missing oid",
+			conf:    barrelman.CheckerConfig{"method": "snmp", "port": a.Port(), "oid": "1.3.6.1.4.1.99999.2.0"},
+			wantErr: "1.3.6.1.4.1.99999.2.0 not found",
+		},
+		{
+			name:    "empty value",
+			conf:    barrelman.CheckerConfig{"method": "snmp", "port": a.Port(), "oid": "1.3.6.1.4.1.99999.1.1.1.0"},
+			wantErr: "1.3.6.1.4.1.99999.1.1.1.0 is empty",
+		},
+		{
+			name:    "wrong community",
+			conf:    barrelman.CheckerConfig{"method": "snmp", "port": a.Port(), "community": "private"},
+			wantErr: "failed to get " + _entPhysicalSerialNum,
+		},
+		{
+			name:    "http without regex",
+			conf:    barrelman.CheckerConfig{"method": "http", "path": "/"},
+			wantErr: "no regex configured",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serial, err := querySerial(d, tt.conf, _testTimeout)
+			switch {
+			case tt.wantErr != "":
+				if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+			case err != nil:
+				t.Fatalf("failed to query serial: %s", err)
+			case serial != tt.want:
+				t.Errorf("got serial %q, want %q", serial, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package serial
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/byuoitav/barrelman"
+)
+
+// ConfigKey is the name of the device CheckerConfig read by the checker.
+// The mac option is the expected MAC address of the device and the serial
+// option is its expected serial number. The method option sets how the serial
+// number is queried (http, telnet, or snmp), and the remaining options depend
+// on the method:
+//
+//	http: url or path, and regex
+//	telnet: port, command, and regex
+//	snmp: oid, port, community, and optionally regex
+//
+// The serial number is the first capture group of regex if it has one, or
+// the whole match if it doesn't. The snmp method uses SNMP v2c to get oid,
+// which defaults to the ENTITY-MIB entPhysicalSerialNum of the chassis, and
+// uses the whole value when no regex is set
+const ConfigKey = "serial"
+
+// Checker queries the identity of the device and compares it to the identity
+// the device is expected to have, to ensure the device hasn't been switched out
+type Checker struct {
+	timeout  int
+	arpTable string
+}
+
+// NewChecker returns a serial checker with the given options set
+func NewChecker(opts ...Option) (*Checker, error) {
+	c := Checker{
+		timeout:  10,
+		arpTable: "/proc/net/arp",
+	}
+
+	// Apply options
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return &c, nil
+}
+
+// Check queries the MAC address and/or serial number of the device,
+// depending on which it is expected to have. If they match then the check is
+// considered healthy. A mismatch means the hardware has been swapped and
+// results in an unhealthy check, as does failing to query the device. Devices
+// with no expected identity are skipped
+func (c *Checker) Check(d *barrelman.Device, forceRecheck bool) barrelman.CheckResult {
+	result := barrelman.CheckResult{
+		RunTime: time.Now(),
+		Passed:  true,
+		Event: barrelman.Event{
+			Device: d,
+			Key:    "hardware",
+			Value:  "Ok",
+		},
+	}
+
+	conf := d.Config(ConfigKey)
+	expectedMAC, checkMAC := conf.String("mac")
+	expectedSerial, checkSerial := conf.String("serial")
+
+	if !checkMAC && !checkSerial {
+		return barrelman.CheckResult{
+			RunTime: time.Now(),
+			Skipped: true,
+			Message: "No identity configured",
+		}
+	}
+
+	timeout := time.Duration(c.timeout) * time.Second
+	found := []string{}
+	swapped := []string{}
+	errs := []string{}
+
+	if checkMAC {
+		mac, err := c.lookupMAC(d.Address, timeout)
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Sprintf("failed to get MAC address: %s", err))
+		case !sameMAC(mac, expectedMAC):
+			swapped = append(swapped, fmt.Sprintf("MAC address is %s, expected %s", mac, expectedMAC))
+		default:
+			found = append(found, "MAC address "+mac)
+		}
+	}
+
+	if checkSerial {
+		serial, err := querySerial(d, conf, timeout)
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Sprintf("failed to get serial number: %s", err))
+		case serial != expectedSerial:
+			swapped = append(swapped, fmt.Sprintf("serial number is %s, expected %s", serial, expectedSerial))
+		default:
+			found = append(found, "serial number "+serial)
+		}
+	}
+
+	if len(found) > 0 {
+		result.Message = "Found expected " + strings.Join(found, " and ")
+	}
+
+	result.Metrics = map[string]barrelman.Metric{
+		"identity-matches":    {Value: float64(len(found))},
+		"identity-mismatches": {Value: float64(len(swapped))},
+	}
+
+	switch {
+	case len(swapped) > 0:
+		result.Passed = false
+		result.Error = "Hardware has been swapped: " + strings.Join(append(swapped, errs...), "; ")
+		result.Event.Value = "Swapped"
+	case len(errs) > 0:
+		result.Passed = false
+		result.Error = strings.Join(errs, "; ")
+		result.Event.Value = "Unknown"
+	}
+
+	return result
+}
+
+// sameMAC compares two MAC addresses regardless of their formatting
+func sameMAC(a, b string) bool {
+	macA, errA := net.ParseMAC(a)
+	macB, errB := net.ParseMAC(b)
+	if errA != nil || errB != nil {
+		return strings.EqualFold(a, b)
+	}
+
+	return macA.String() == macB.String()
+}
@@ -0,0 +1,46 @@
+package shure
+
+// Option is a function which modifies a given checker, allowing the
+// user to have an option on how to setup the checker
+type Option func(*Checker)
+
+// WithPort allows the user to set the port the receiver's control strings
+// are sent to. The default is 2202
+func WithPort(p int) Option {
+	return func(c *Checker) {
+		c.port = p
+	}
+}
+
+// WithTimeout allows the user to set the timeout (in seconds) of the
+// connection to the receiver. The default is 5 seconds
+func WithTimeout(t int) Option {
+	return func(c *Checker) {
+		c.timeout = t
+	}
+}
+
+// WithChannels allows the user to set the number of channels read from each
+// receiver. The default is 1
+func WithChannels(n int) Option {
+	return func(c *Checker) {
+		c.channels = n
+	}
+}
+
+// WithLowBattery allows the user to set the battery level (in bars, 0-5) at
+// or below which a channel is considered unhealthy. The default is 1
+func WithLowBattery(bars int) Option {
+	return func(c *Checker) {
+		c.lowBattery = bars
+	}
+}
+
+// WithMinRFLevel allows the user to set the lowest RF signal level (in dBm)
+// a channel with an active transmitter may have before it is considered
+// unhealthy. 0, the default, disables the limit
+func WithMinRFLevel(dBm int) Option {
+	return func(c *Checker) {
+		c.minRFLevel = dBm
+	}
+}
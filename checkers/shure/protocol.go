@@ -0,0 +1,67 @@
+package shure
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Parameters read from each channel of the receiver
+const (
+	_battBars     = "BATT_BARS"
+	_rfLevel      = "RX_RF_LVL"
+	_interference = "INTERFERENCE_STATUS"
+)
+
+// _unknown is the value the receiver reports when it has no reading, e.g.
+// when no transmitter is linked to the channel
+const _unknown = "255"
+
+// _rfOffset is subtracted from the receiver's RX_RF_LVL to get dBm
+const _rfOffset = 128
+
+// reply is a parsed "< REP channel parameter value >" string
+type reply struct {
+	channel   int
+	parameter string
+	value     string
+}
+
+// get formats a request for a parameter on a channel
+func get(channel int, parameter string) string {
+	return fmt.Sprintf("< GET %d %s >", channel, parameter)
+}
+
+// readReply reads strings from r until it finds a report, skipping anything
+// else the receiver sends (e.g. sample strings)
+func readReply(r *bufio.Reader) (reply, error) {
+	for {
+		s, err := r.ReadString('>')
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return reply{}, err
+		}
+
+		if i := strings.IndexByte(s, '<'); i >= 0 {
+			s = s[i+1:]
+		}
+
+		fields := strings.Fields(strings.TrimSuffix(s, ">"))
+		if len(fields) < 4 || fields[0] != "REP" {
+			continue
+		}
+
+		var rep reply
+		if _, err := fmt.Sscanf(fields[1], "%d", &rep.channel); err != nil {
+			// Device level reports have no channel
+			continue
+		}
+
+		rep.parameter = fields[2]
+		rep.value = strings.Join(fields[3:], " ")
+		return rep, nil
+	}
+}
@@ -0,0 +1,206 @@
+package shure
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/byuoitav/barrelman"
+)
+
+// ConfigKey is the name of the device CheckerConfig read by the checker.
+// The port, timeout, channels, lowBattery, and minRFLevel options override
+// the checker's own settings for the device
+const ConfigKey = "shure"
+
+// Checker reads the battery level, RF signal level, and interference status
+// of each channel of a Shure wireless receiver using its network control
+// strings
+type Checker struct {
+	port       int
+	timeout    int
+	channels   int
+	lowBattery int
+	minRFLevel int
+}
+
+// channel is the state of a single receiver channel
+type channel struct {
+	battery      string
+	rfLevel      string
+	interference string
+}
+
+// NewChecker returns a shure checker with the given options set
+func NewChecker(opts ...Option) (*Checker, error) {
+	c := Checker{
+		port:       2202,
+		timeout:    5,
+		channels:   1,
+		lowBattery: 1,
+	}
+
+	// Apply options
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	if c.channels < 1 {
+		return nil, fmt.Errorf("Invalid channel count: %d", c.channels)
+	}
+
+	return &c, nil
+}
+
+// Check reads the state of every channel on the receiver, emitting a
+// battery-level, rf-level, and interference event for each one. If no channel
+// has a low battery, weak signal, or interference then the check is
+// considered healthy. Channels without a linked transmitter are not held to
+// the battery or signal limits
+func (c *Checker) Check(d *barrelman.Device, forceRecheck bool) barrelman.CheckResult {
+	result := barrelman.CheckResult{
+		RunTime: time.Now(),
+		Passed:  true,
+		Event: barrelman.Event{
+			Device: d,
+			Key:    "receiver",
+			Value:  "Ok",
+		},
+	}
+
+	// Apply any device specific settings
+	port, timeout, count, lowBattery, minRFLevel := c.port, c.timeout, c.channels, c.lowBattery, c.minRFLevel
+	conf := d.Config(ConfigKey)
+	if i, ok := conf.Int("port"); ok && i > 0 {
+		port = i
+	}
+	if i, ok := conf.Int("timeout"); ok && i > 0 {
+		timeout = i
+	}
+	if i, ok := conf.Int("channels"); ok && i > 0 {
+		count = i
+	}
+	if i, ok := conf.Int("lowBattery"); ok {
+		lowBattery = i
+	}
+	if i, ok := conf.Int("minRFLevel"); ok {
+		minRFLevel = i
+	}
+
+	channels, err := query(net.JoinHostPort(d.Address, strconv.Itoa(port)), count, time.Duration(timeout)*time.Second)
+	if err != nil {
+		result.Passed = false
+		result.Error = fmt.Sprintf("Failed to query receiver: %s", err)
+		result.Event.Value = "Unreachable"
+		return result
+	}
+
+	summary := []string{}
+	problems := []string{}
+	result.Metrics = make(map[string]barrelman.Metric)
+	for i, ch := range channels {
+		num := i + 1
+		battery, rfLevel, interference := "Unknown", "Unknown", ch.interference
+		linked := ch.battery != _unknown
+
+		if bars, err := strconv.Atoi(ch.battery); err == nil && linked {
+			battery = strconv.Itoa(bars)
+			result.Metrics[fmt.Sprintf("battery-level-%d", num)] = barrelman.Metric{Value: float64(bars), Unit: "bars"}
+			if bars <= lowBattery {
+				problems = append(problems, fmt.Sprintf("Channel %d battery is at %d bars", num, bars))
+			}
+		}
+
+		if lvl, err := strconv.Atoi(ch.rfLevel); err == nil {
+			dBm := lvl - _rfOffset
+			rfLevel = strconv.Itoa(dBm)
+			result.Metrics[fmt.Sprintf("rf-level-%d", num)] = barrelman.Metric{Value: float64(dBm), Unit: "dBm"}
+			if linked && minRFLevel != 0 && dBm < minRFLevel {
+				problems = append(problems, fmt.Sprintf("Channel %d RF level is %ddBm", num, dBm))
+			}
+		}
+
+		if interference == "" {
+			interference = "Unknown"
+		} else if interference != "NONE" {
+			problems = append(problems, fmt.Sprintf("Channel %d has %s interference", num, strings.ToLower(interference)))
+		}
+
+		summary = append(summary, fmt.Sprintf("%d: battery %s, rf %s, interference %s", num, battery, rfLevel, interference))
+		result.Events = append(result.Events,
+			barrelman.Event{Device: d, Key: fmt.Sprintf("battery-level-%d", num), Value: battery},
+			barrelman.Event{Device: d, Key: fmt.Sprintf("rf-level-%d", num), Value: rfLevel},
+			barrelman.Event{Device: d, Key: fmt.Sprintf("interference-%d", num), Value: interference},
+		)
+	}
+
+	result.Message = "Channel " + strings.Join(summary, "; channel ")
+
+	if len(problems) > 0 {
+		result.Passed = false
+		result.Error = strings.Join(problems, ", ")
+		result.Event.Value = "Degraded"
+	}
+
+	return result
+}
+
+// query asks the receiver at addr for the state of the given number of
+// channels, returning once every parameter has been reported
+func query(addr string, count int, timeout time.Duration) ([]channel, error) {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	params := []string{_battBars, _rfLevel, _interference}
+	var req strings.Builder
+	for ch := 1; ch <= count; ch++ {
+		for _, p := range params {
+			req.WriteString(get(ch, p))
+		}
+	}
+
+	if _, err := conn.Write([]byte(req.String())); err != nil {
+		return nil, fmt.Errorf("Failed to send request: %w", err)
+	}
+
+	channels := make([]channel, count)
+	r := bufio.NewReader(conn)
+	for remaining := count * len(params); remaining > 0; {
+		rep, err := readReply(r)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read reply: %w", err)
+		}
+
+		if rep.channel < 1 || rep.channel > count {
+			continue
+		}
+
+		ch := &channels[rep.channel-1]
+		var field *string
+		switch rep.parameter {
+		case _battBars:
+			field = &ch.battery
+		case _rfLevel:
+			field = &ch.rfLevel
+		case _interference:
+			field = &ch.interference
+		default:
+			continue
+		}
+
+		if *field == "" {
+			remaining--
+		}
+		*field = rep.value
+	}
+
+	return channels, nil
+}
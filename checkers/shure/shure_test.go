@@ -0,0 +1,367 @@
+package shure
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/byuoitav/barrelman"
+)
+
+// receiver is a fake Shure receiver listening on a loopback port. It waits
+// for every expected GET before answering, so that replies can be sent in
+// any order
+type receiver struct {
+	ln net.Listener
+
+	// values maps "channel PARAM" to the value reported for it. Parameters
+	// without a value are never answered
+	values map[string]string
+
+	// expect is the number of GETs the receiver waits for before replying
+	expect int
+
+	// reverse sends the replies in the opposite order they were requested
+	reverse bool
+
+	// samples are sent before every reply, as receivers do when metering
+	samples bool
+}
+
+func newReceiver(t *testing.T, channels int, values map[string]string) *receiver {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	return &receiver{
+		ln:     ln,
+		values: values,
+		expect: channels * 3,
+	}
+}
+
+func (r *receiver) port() int {
+	return r.ln.Addr().(*net.TCPAddr).Port
+}
+
+func (r *receiver) serve() {
+	for {
+		conn, err := r.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		go r.handle(conn)
+	}
+}
+
+func (r *receiver) handle(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	requests := []string{}
+	for len(requests) < r.expect {
+		s, err := reader.ReadString('>')
+		if err != nil {
+			return
+		}
+
+		fields := strings.Fields(strings.Trim(strings.TrimSpace(s), "<>"))
+		if len(fields) != 3 || fields[0] != "GET" {
+			return
+		}
+
+		requests = append(requests, fields[1]+" "+fields[2])
+	}
+
+	if r.reverse {
+		for i, j := 0, len(requests)-1; i < j; i, j = i+1, j-1 {
+			requests[i], requests[j] = requests[j], requests[i]
+		}
+	}
+
+	var out strings.Builder
+	for _, req := range requests {
+		value, ok := r.values[req]
+		if !ok {
+			continue
+		}
+
+		if r.samples {
+			channel := strings.Fields(req)[0]
+			fmt.Fprintf(&out, "< SAMPLE %s ALL XB 050 045 >", channel)
+		}
+
+		fmt.Fprintf(&out, "< REP %s %s >", req, value)
+	}
+
+	conn.Write([]byte(out.String()))
+
+	// Hold the connection open until the checker is done with it
+	reader.ReadByte()
+}
+
+// healthy returns the values of a receiver with every channel in good shape
+func healthy(channels int) map[string]string {
+	values := make(map[string]string)
+	for ch := 1; ch <= channels; ch++ {
+		values[fmt.Sprintf("%d BATT_BARS", ch)] = "004"
+		values[fmt.Sprintf("%d RX_RF_LVL", ch)] = "090"
+		values[fmt.Sprintf("%d INTERFERENCE_STATUS", ch)] = "NONE"
+	}
+
+	return values
+}
+
+func check(t *testing.T, r *receiver, opts ...Option) barrelman.CheckResult {
+	t.Helper()
+
+	go r.serve()
+
+	opts = append([]Option{WithPort(r.port()), WithTimeout(1)}, opts...)
+	c, err := NewChecker(opts...)
+	if err != nil {
+		t.Fatalf("failed to create checker: %s", err)
+	}
+
+	return c.Check(&barrelman.Device{Name: "ITB-1101-RCV1", Address: "127.0.0.1"}, false)
+}
+
+// events returns the result's additional events keyed by event key
+func events(result barrelman.CheckResult) map[string]string {
+	m := make(map[string]string, len(result.Events))
+	for _, e := range result.Events {
+		m[e.Key] = e.Value
+	}
+
+	return m
+}
+
+func TestCheckHealthy(t *testing.T) {
+	result := check(t, newReceiver(t, 1, healthy(1)))
+	if !result.Passed {
+		t.Fatalf("expected check to pass, got error %q", result.Error)
+	}
+
+	if result.Event.Key != "receiver" || result.Event.Value != "Ok" {
+		t.Errorf("unexpected event %s=%s", result.Event.Key, result.Event.Value)
+	}
+
+	want := map[string]string{
+		"battery-level-1": "4",
+		"rf-level-1":      "-38",
+		"interference-1":  "NONE",
+	}
+	if got := events(result); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got events %v, want %v", got, want)
+	}
+
+	if m := result.Metrics["rf-level-1"]; m.Value != -38 || m.Unit != "dBm" {
+		t.Errorf("unexpected rf metric %+v", m)
+	}
+}
+
+func TestCheckOutOfOrder(t *testing.T) {
+	values := healthy(4)
+	values["3 BATT_BARS"] = "002"
+	values["4 RX_RF_LVL"] = "070"
+
+	r := newReceiver(t, 4, values)
+	r.reverse = true
+
+	result := check(t, r, WithChannels(4))
+	if !result.Passed {
+		t.Fatalf("expected check to pass, got error %q", result.Error)
+	}
+
+	got := events(result)
+	if len(got) != 12 {
+		t.Fatalf("expected 12 events, got %d: %v", len(got), got)
+	}
+
+	if got["battery-level-3"] != "2" || got["battery-level-1"] != "4" {
+		t.Errorf("battery levels assigned to the wrong channels: %v", got)
+	}
+	if got["rf-level-4"] != "-58" || got["rf-level-2"] != "-38" {
+		t.Errorf("rf levels assigned to the wrong channels: %v", got)
+	}
+
+	if !strings.HasPrefix(result.Message, "Channel 1: battery 4") {
+		t.Errorf("unexpected message %q", result.Message)
+	}
+}
+
+func TestCheckSkipsSamples(t *testing.T) {
+	r := newReceiver(t, 2, healthy(2))
+	r.samples = true
+
+	result := check(t, r, WithChannels(2))
+	if !result.Passed {
+		t.Fatalf("expected check to pass, got error %q", result.Error)
+	}
+
+	if got := events(result); got["battery-level-2"] != "4" || got["interference-2"] != "NONE" {
+		t.Errorf("unexpected events %v", got)
+	}
+}
+
+func TestCheckNoTransmitter(t *testing.T) {
+	values := healthy(2)
+	values["2 BATT_BARS"] = "255"
+	values["2 RX_RF_LVL"] = "000"
+
+	result := check(t, newReceiver(t, 2, values), WithChannels(2), WithMinRFLevel(-80))
+	if !result.Passed {
+		t.Fatalf("expected an unlinked channel to pass, got error %q", result.Error)
+	}
+
+	if got := events(result); got["battery-level-2"] != "Unknown" {
+		t.Errorf("got battery level %q, want Unknown", got["battery-level-2"])
+	}
+
+	if _, ok := result.Metrics["battery-level-2"]; ok {
+		t.Errorf("expected no battery metric for an unlinked channel")
+	}
+}
+
+func TestCheckFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[string]string
+		opts   []Option
+		want   string
+	}{
+		{
+			name:   "low battery",
+			values: map[string]string{"2 BATT_BARS": "001"},
+			want:   "Channel 2 battery is at 1 bars",
+		},
+		{
+			name:   "raised low battery",
+			values: map[string]string{"1 BATT_BARS": "002"},
+			opts:   []Option{WithLowBattery(2)},
+			want:   "Channel 1 battery is at 2 bars",
+		},
+		{
+			name:   "weak rf",
+			values: map[string]string{"2 RX_RF_LVL": "060"},
+			opts:   []Option{WithMinRFLevel(-60)},
+			want:   "Channel 2 RF level is -68dBm",
+		},
+		{
+			name:   "interference",
+			values: map[string]string{"1 INTERFERENCE_STATUS": "CRITICAL"},
+			want:   "Channel 1 has critical interference",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := healthy(2)
+			for k, v := range tt.values {
+				values[k] = v
+			}
+
+			opts := append([]Option{WithChannels(2)}, tt.opts...)
+			result := check(t, newReceiver(t, 2, values), opts...)
+			if result.Passed {
+				t.Fatalf("expected check to fail")
+			}
+
+			if result.Error != tt.want {
+				t.Errorf("got error %q, want %q", result.Error, tt.want)
+			}
+
+			if result.Event.Value != "Degraded" {
+				t.Errorf("got event value %q, want Degraded", result.Event.Value)
+			}
+
+			// Events are still reported for every channel
+			if got := events(result); len(got) != 6 {
+				t.Errorf("expected 6 events, got %v", got)
+			}
+		})
+	}
+}
+
+func TestCheckMissingParameter(t *testing.T) {
+	values := healthy(2)
+	delete(values, "2 INTERFERENCE_STATUS")
+
+	start := time.Now()
+	result := check(t, newReceiver(t, 2, values), WithChannels(2))
+	if result.Passed {
+		t.Fatalf("expected check to fail")
+	}
+
+	if elapsed := time.Since(start); elapsed < time.Second || elapsed > 3*time.Second {
+		t.Errorf("check took %s, expected about the 1s timeout", elapsed)
+	}
+
+	if !strings.HasPrefix(result.Error, "Failed to query receiver: Failed to read reply") {
+		t.Errorf("unexpected error %q", result.Error)
+	}
+
+	if result.Event.Value != "Unreachable" {
+		t.Errorf("got event value %q, want Unreachable", result.Event.Value)
+	}
+}
+
+func TestCheckDeviceConfig(t *testing.T) {
+	r := newReceiver(t, 2, healthy(2))
+	go r.serve()
+
+	c, err := NewChecker(WithTimeout(1))
+	if err != nil {
+		t.Fatalf("failed to create checker: %s", err)
+	}
+
+	d := &barrelman.Device{
+		Name:    "ITB-1101-RCV1",
+		Address: "127.0.0.1",
+		CheckerConfig: map[string]barrelman.CheckerConfig{
+			ConfigKey: {"port": r.port(), "channels": 2},
+		},
+	}
+
+	result := c.Check(d, false)
+	if !result.Passed {
+		t.Fatalf("expected check to pass, got error %q", result.Error)
+	}
+
+	if got := events(result); len(got) != 6 {
+		t.Errorf("expected 6 events, got %v", got)
+	}
+}
+
+func TestReadReply(t *testing.T) {
+	input := "< SAMPLE 1 ALL XB 050 045 >< REP DEVICE_ID {ULXD4Q} >< REP 2 BATT_BARS >\r\n< REP 3 INTERFERENCE_STATUS CRITICAL >"
+	r := bufio.NewReader(strings.NewReader(input))
+
+	rep, err := readReply(r)
+	if err != nil {
+		t.Fatalf("failed to read reply: %s", err)
+	}
+
+	want := reply{channel: 3, parameter: "INTERFERENCE_STATUS", value: "CRITICAL"}
+	if rep != want {
+		t.Errorf("got %+v, want %+v", rep, want)
+	}
+
+	if _, err := readReply(r); err == nil {
+		t.Errorf("expected an error at the end of input")
+	}
+}
+
+func TestNewCheckerInvalidChannels(t *testing.T) {
+	if _, err := NewChecker(WithChannels(0)); err == nil {
+		t.Fatalf("expected an error")
+	}
+}
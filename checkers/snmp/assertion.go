@@ -0,0 +1,88 @@
+package snmp
+
+import (
+	"fmt"
+)
+
+// Assertion is a condition that the value of an OID must meet
+type Assertion struct {
+	// Name is a human readable name for the OID, used in messages
+	Name string
+
+	// OID is the object identifier to get, for example 1.3.6.1.2.1.2.2.1.8.1
+	OID string
+
+	// Op is the comparison to make, one of eq, ne, lt, le, gt, or ge. eq and
+	// ne compare the values as strings, and the rest compare them as numbers
+	Op string
+
+	// Value is the value to compare the OID's value to
+	Value string
+}
+
+func (a Assertion) name() string {
+	if a.Name != "" {
+		return a.Name
+	}
+
+	return a.OID
+}
+
+func (a Assertion) validate() error {
+	if a.OID == "" {
+		return fmt.Errorf("assertion %q has no oid", a.Name)
+	}
+
+	switch a.Op {
+	case "eq", "ne":
+	case "lt", "le", "gt", "ge":
+		if _, err := parseFloat(a.Value); err != nil {
+			return fmt.Errorf("assertion %s must have a numeric value for %s", a.name(), a.Op)
+		}
+	default:
+		return fmt.Errorf("assertion %s has unknown op %q", a.name(), a.Op)
+	}
+
+	return nil
+}
+
+// check returns an error if the given value doesn't meet the assertion
+func (a Assertion) check(value string) error {
+	switch a.Op {
+	case "eq":
+		if value != a.Value {
+			return fmt.Errorf("%s is %s, expected %s", a.name(), value, a.Value)
+		}
+		return nil
+	case "ne":
+		if value == a.Value {
+			return fmt.Errorf("%s is %s", a.name(), value)
+		}
+		return nil
+	}
+
+	got, err := parseFloat(value)
+	if err != nil {
+		return fmt.Errorf("%s is %s, expected a number", a.name(), value)
+	}
+
+	want, _ := parseFloat(a.Value)
+
+	var ok bool
+	switch a.Op {
+	case "lt":
+		ok = got < want
+	case "le":
+		ok = got <= want
+	case "gt":
+		ok = got > want
+	case "ge":
+		ok = got >= want
+	}
+
+	if !ok {
+		return fmt.Errorf("%s is %s, expected %s %s", a.name(), value, a.Op, a.Value)
+	}
+
+	return nil
+}
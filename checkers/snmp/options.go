@@ -0,0 +1,61 @@
+package snmp
+
+// Option is a function which modifies a given checker, allowing the
+// user to have an option on how to setup the checker
+type Option func(*Checker)
+
+// WithPort allows the user to set the port SNMP requests are sent to. The
+// default is 161
+func WithPort(p int) Option {
+	return func(c *Checker) {
+		c.port = p
+	}
+}
+
+// WithTimeout allows the user to set the timeout (in seconds) of each SNMP
+// request. The default is 5 seconds
+func WithTimeout(t int) Option {
+	return func(c *Checker) {
+		c.timeout = t
+	}
+}
+
+// WithRetries allows the user to set the number of times a request is
+// retried after a timeout. The default is 1
+func WithRetries(r int) Option {
+	return func(c *Checker) {
+		c.retries = r
+	}
+}
+
+// WithCommunity allows the user to use SNMP v2c with the given community.
+// This is the default, with the community public
+func WithCommunity(community string) Option {
+	return func(c *Checker) {
+		c.version = "2c"
+		c.community = community
+	}
+}
+
+// WithV3 allows the user to use SNMP v3 with the given user security model
+// settings. The auth protocol is one of MD5, SHA, SHA224, SHA256, SHA384, or
+// SHA512 and the privacy protocol is one of DES, AES, AES192, AES256,
+// AES192C, or AES256C. Leaving a protocol empty disables it
+func WithV3(user, authProtocol, authPassword, privProtocol, privPassword string) Option {
+	return func(c *Checker) {
+		c.version = "3"
+		c.user = user
+		c.authProtocol = authProtocol
+		c.authPassword = authPassword
+		c.privProtocol = privProtocol
+		c.privPassword = privPassword
+	}
+}
+
+// WithAssertions allows the user to set the assertions made on every
+// device. Devices can override these through their CheckerConfig
+func WithAssertions(a ...Assertion) Option {
+	return func(c *Checker) {
+		c.assertions = a
+	}
+}
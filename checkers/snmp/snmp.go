@@ -0,0 +1,368 @@
+package snmp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/byuoitav/barrelman"
+	"github.com/gosnmp/gosnmp"
+)
+
+// ConfigKey is the name of the device CheckerConfig read by the checker.
+// The port, timeout, version, community, user, authProtocol, authPassword,
+// privProtocol, privPassword, and assertions options override the checker's
+// own settings for the device. Assertions are given as a list of objects with
+// the same fields as an Assertion
+const ConfigKey = "snmp"
+
+// Checker queries the device over SNMP and makes assertions on the values of
+// the configured OIDs
+type Checker struct {
+	port    int
+	timeout int
+	retries int
+
+	version      string
+	community    string
+	user         string
+	authProtocol string
+	authPassword string
+	privProtocol string
+	privPassword string
+
+	assertions []Assertion
+}
+
+// NewChecker returns an SNMP checker with the given options set
+func NewChecker(opts ...Option) (*Checker, error) {
+	c := Checker{
+		port:      161,
+		timeout:   5,
+		retries:   1,
+		version:   "2c",
+		community: "public",
+	}
+
+	// Apply options
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	for _, a := range c.assertions {
+		if err := a.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := c.client(&barrelman.Device{}); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// Check gets all of the asserted OIDs from the device in a single request.
+// If every assertion holds then the check is considered healthy. A failed
+// request or any failed assertion will result in an unhealthy check. Devices
+// with no assertions are skipped
+func (c *Checker) Check(d *barrelman.Device, forceRecheck bool) barrelman.CheckResult {
+	result := barrelman.CheckResult{
+		RunTime: time.Now(),
+		Passed:  true,
+		Event: barrelman.Event{
+			Device: d,
+			Key:    "snmp",
+			Value:  "Ok",
+		},
+	}
+
+	fail := func(format string, a ...interface{}) barrelman.CheckResult {
+		result.Passed = false
+		result.Error = fmt.Sprintf(format, a...)
+		result.Event.Value = "Failed"
+		return result
+	}
+
+	assertions, err := c.deviceAssertions(d)
+	if err != nil {
+		return fail("Invalid config: %s", err)
+	}
+
+	if len(assertions) == 0 {
+		return barrelman.CheckResult{
+			RunTime: time.Now(),
+			Skipped: true,
+			Message: "No assertions to check",
+		}
+	}
+
+	client, err := c.client(d)
+	if err != nil {
+		return fail("Invalid config: %s", err)
+	}
+
+	if err := client.Connect(); err != nil {
+		return fail("Failed to connect: %s", err)
+	}
+	defer client.Conn.Close()
+
+	oids := make([]string, 0, len(assertions))
+	for _, a := range assertions {
+		oids = append(oids, a.OID)
+	}
+
+	packet, err := client.Get(oids)
+	if err != nil {
+		return fail("Failed to get OIDs: %s", err)
+	}
+
+	if packet.Error != gosnmp.NoError {
+		return fail("Device returned error %s", packet.Error)
+	}
+
+	values := make(map[string]gosnmp.SnmpPDU, len(packet.Variables))
+	for _, v := range packet.Variables {
+		values[strings.TrimPrefix(v.Name, ".")] = v
+	}
+
+	found := []string{}
+	failed := []string{}
+	for _, a := range assertions {
+		pdu, ok := values[strings.TrimPrefix(a.OID, ".")]
+		if !ok || pdu.Type == gosnmp.NoSuchObject || pdu.Type == gosnmp.NoSuchInstance || pdu.Type == gosnmp.Null {
+			failed = append(failed, fmt.Sprintf("%s not found", a.name()))
+			continue
+		}
+
+		value := toString(pdu)
+		found = append(found, fmt.Sprintf("%s=%s", a.name(), value))
+
+		// Numeric values are also reported as metrics
+		if f, err := parseFloat(value); err == nil {
+			if result.Metrics == nil {
+				result.Metrics = make(map[string]barrelman.Metric)
+			}
+			result.Metrics[a.name()] = barrelman.Metric{Value: f}
+		}
+
+		if err := a.check(value); err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+
+	result.Message = strings.Join(found, ", ")
+	if len(failed) > 0 {
+		return fail("%s", strings.Join(failed, "; "))
+	}
+
+	return result
+}
+
+// client builds the SNMP client for the given device by applying any device
+// specific settings to the checker's settings
+func (c *Checker) client(d *barrelman.Device) (*gosnmp.GoSNMP, error) {
+	conf := d.Config(ConfigKey)
+
+	// Settings that aren't strings are invalid rather than ignored, so that a
+	// typo doesn't silently fall back to the checker's settings
+	var invalid []string
+	setting := func(key, def string) string {
+		v, ok := conf[key]
+		if !ok {
+			return def
+		}
+
+		s, ok := v.(string)
+		if !ok {
+			invalid = append(invalid, key)
+			return def
+		}
+
+		return s
+	}
+
+	port, timeout := c.port, c.timeout
+	if p, ok := conf.Int("port"); ok && p > 0 {
+		port = p
+	}
+	if t, ok := conf.Int("timeout"); ok && t > 0 {
+		timeout = t
+	}
+
+	client := &gosnmp.GoSNMP{
+		Target:  d.Address,
+		Port:    uint16(port),
+		Timeout: time.Duration(timeout) * time.Second,
+		Retries: c.retries,
+		MaxOids: gosnmp.MaxOids,
+	}
+
+	version, err := deviceVersion(conf, c.version)
+	if err != nil {
+		return nil, err
+	}
+
+	switch version {
+	case "2c":
+		client.Version = gosnmp.Version2c
+		client.Community = setting("community", c.community)
+	case "3":
+		auth, err := authProtocol(setting("authProtocol", c.authProtocol))
+		if err != nil {
+			return nil, err
+		}
+
+		priv, err := privProtocol(setting("privProtocol", c.privProtocol))
+		if err != nil {
+			return nil, err
+		}
+
+		client.Version = gosnmp.Version3
+		client.SecurityModel = gosnmp.UserSecurityModel
+		client.SecurityParameters = &gosnmp.UsmSecurityParameters{
+			UserName:                 setting("user", c.user),
+			AuthenticationProtocol:   auth,
+			AuthenticationPassphrase: setting("authPassword", c.authPassword),
+			PrivacyProtocol:          priv,
+			PrivacyPassphrase:        setting("privPassword", c.privPassword),
+		}
+
+		switch {
+		case priv != gosnmp.NoPriv && auth == gosnmp.NoAuth:
+			return nil, fmt.Errorf("privacy requires authentication")
+		case priv != gosnmp.NoPriv:
+			client.MsgFlags = gosnmp.AuthPriv
+		case auth != gosnmp.NoAuth:
+			client.MsgFlags = gosnmp.AuthNoPriv
+		default:
+			client.MsgFlags = gosnmp.NoAuthNoPriv
+		}
+	default:
+		return nil, fmt.Errorf("unsupported version %q", version)
+	}
+
+	if len(invalid) > 0 {
+		return nil, fmt.Errorf("%s must be strings", strings.Join(invalid, ", "))
+	}
+
+	return client, nil
+}
+
+// deviceVersion returns the SNMP version set in the device's config, or def
+// if it isn't set. Numeric versions are accepted since YAML and JSON decode
+// an unquoted 3 as a number, and 2 is taken to mean 2c
+func deviceVersion(conf barrelman.CheckerConfig, def string) (string, error) {
+	switch v := conf["version"].(type) {
+	case nil:
+		return def, nil
+	case string:
+		return v, nil
+	case int, int64, float64:
+		switch fmt.Sprint(v) {
+		case "2":
+			return "2c", nil
+		case "3":
+			return "3", nil
+		}
+	}
+
+	return "", fmt.Errorf("unsupported version %v", conf["version"])
+}
+
+// deviceAssertions returns the assertions for the given device
+func (c *Checker) deviceAssertions(d *barrelman.Device) ([]Assertion, error) {
+	raw, ok := d.Config(ConfigKey)["assertions"].([]interface{})
+	if !ok {
+		return c.assertions, nil
+	}
+
+	assertions := make([]Assertion, 0, len(raw))
+	for _, r := range raw {
+		conf, ok := r.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("assertions must be objects")
+		}
+
+		a := Assertion{}
+		a.Name, _ = barrelman.CheckerConfig(conf).String("name")
+		a.OID, _ = barrelman.CheckerConfig(conf).String("oid")
+		a.Op, _ = barrelman.CheckerConfig(conf).String("op")
+		if v, ok := conf["value"]; ok {
+			a.Value = fmt.Sprint(v)
+		}
+
+		if err := a.validate(); err != nil {
+			return nil, err
+		}
+
+		assertions = append(assertions, a)
+	}
+
+	return assertions, nil
+}
+
+// toString converts the value of the PDU to a string
+func toString(pdu gosnmp.SnmpPDU) string {
+	switch v := pdu.Value.(type) {
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	}
+
+	switch pdu.Type {
+	case gosnmp.Integer, gosnmp.Counter32, gosnmp.Gauge32, gosnmp.TimeTicks, gosnmp.Counter64, gosnmp.Uinteger32:
+		return gosnmp.ToBigInt(pdu.Value).String()
+	}
+
+	return fmt.Sprint(pdu.Value)
+}
+
+func authProtocol(p string) (gosnmp.SnmpV3AuthProtocol, error) {
+	switch strings.ToUpper(p) {
+	case "":
+		return gosnmp.NoAuth, nil
+	case "MD5":
+		return gosnmp.MD5, nil
+	case "SHA":
+		return gosnmp.SHA, nil
+	case "SHA224":
+		return gosnmp.SHA224, nil
+	case "SHA256":
+		return gosnmp.SHA256, nil
+	case "SHA384":
+		return gosnmp.SHA384, nil
+	case "SHA512":
+		return gosnmp.SHA512, nil
+	}
+
+	return gosnmp.NoAuth, fmt.Errorf("unsupported auth protocol %q", p)
+}
+
+func privProtocol(p string) (gosnmp.SnmpV3PrivProtocol, error) {
+	switch strings.ToUpper(p) {
+	case "":
+		return gosnmp.NoPriv, nil
+	case "DES":
+		return gosnmp.DES, nil
+	case "AES":
+		return gosnmp.AES, nil
+	case "AES192":
+		return gosnmp.AES192, nil
+	case "AES256":
+		return gosnmp.AES256, nil
+	case "AES192C":
+		return gosnmp.AES192C, nil
+	case "AES256C":
+		return gosnmp.AES256C, nil
+	}
+
+	return gosnmp.NoPriv, fmt.Errorf("unsupported privacy protocol %q", p)
+}
+
+// parseFloat parses a value for a numeric comparison
+func parseFloat(s string) (float64, error) {
+	return strconv.ParseFloat(strings.TrimSpace(s), 64)
+}
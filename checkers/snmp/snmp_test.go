@@ -0,0 +1,403 @@
+package snmp
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/byuoitav/barrelman"
+	"github.com/byuoitav/barrelman/internal/snmptest"
+	"github.com/gosnmp/gosnmp"
+)
+
+const (
+	_sysName      = "1.3.6.1.2.1.1.5.0"
+	_ifOperStatus = "1.3.6.1.2.1.2.2.1.8.1"
+	_temperature  = "1.3.6.1.4.1.9.9.13.1.3.1.3.1"
+	_noObject     = "1.3.6.1.4.1.99999.1.0"
+	_noInstance   = "1.3.6.1.2.1.2.2.1.8.99"
+)
+
+// newAgent returns a stand-in agent with a switch's values
+func newAgent(t *testing.T) *snmptest.Agent {
+	t.Helper()
+
+	return snmptest.NewAgent(t, map[string]gosnmp.SnmpPDU{
+		_sysName:      {Type: gosnmp.OctetString, Value: []byte("core-switch")},
+		_ifOperStatus: {Type: gosnmp.Integer, Value: 1},
+		_temperature:  {Type: gosnmp.Gauge32, Value: uint32(42)},
+		_noObject:     {Type: gosnmp.NoSuchObject},
+	})
+}
+
+func testDevice(conf barrelman.CheckerConfig) *barrelman.Device {
+	d := &barrelman.Device{
+		Name:    "ITB-1101-SW1",
+		Address: "127.0.0.1",
+	}
+
+	if conf != nil {
+		d.CheckerConfig = map[string]barrelman.CheckerConfig{ConfigKey: conf}
+	}
+
+	return d
+}
+
+func newTestChecker(t *testing.T, a *snmptest.Agent, opts ...Option) *Checker {
+	t.Helper()
+
+	go a.Serve()
+
+	opts = append([]Option{WithPort(a.Port()), WithTimeout(1), WithRetries(0)}, opts...)
+	c, err := NewChecker(opts...)
+	if err != nil {
+		t.Fatalf("failed to create checker: %s", err)
+	}
+
+	return c
+}
+
+var _passingAssertions = []Assertion{
+	{Name: "sysName", OID: _sysName, Op: "eq", Value: "core-switch"},
+	{Name: "ifOperStatus", OID: "." + _ifOperStatus, Op: "eq", Value: "1"},
+	{Name: "temperature", OID: _temperature, Op: "lt", Value: "60"},
+}
+
+func TestCheckV2c(t *testing.T) {
+	c := newTestChecker(t, newAgent(t), WithAssertions(_passingAssertions...))
+
+	result := c.Check(testDevice(nil), false)
+	if !result.Passed {
+		t.Fatalf("expected check to pass, got error %q", result.Error)
+	}
+
+	if result.Event.Key != "snmp" || result.Event.Value != "Ok" {
+		t.Errorf("unexpected event %s=%s", result.Event.Key, result.Event.Value)
+	}
+
+	want := "sysName=core-switch, ifOperStatus=1, temperature=42"
+	if result.Message != want {
+		t.Errorf("got message %q, want %q", result.Message, want)
+	}
+
+	if m, ok := result.Metrics["temperature"]; !ok || m.Value != 42 {
+		t.Errorf("expected temperature metric of 42, got %+v", result.Metrics)
+	}
+	if _, ok := result.Metrics["sysName"]; ok {
+		t.Errorf("expected no metric for non-numeric value")
+	}
+}
+
+func TestCheckV2cWrongCommunity(t *testing.T) {
+	c := newTestChecker(t, newAgent(t), WithCommunity("private"), WithAssertions(_passingAssertions...))
+
+	result := c.Check(testDevice(nil), false)
+	if result.Passed {
+		t.Fatalf("expected check to fail")
+	}
+
+	if !strings.HasPrefix(result.Error, "Failed to get OIDs") {
+		t.Errorf("unexpected error %q", result.Error)
+	}
+}
+
+func TestCheckV3(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags gosnmp.SnmpV3MsgFlags
+		user  *gosnmp.UsmSecurityParameters
+		opt   Option
+	}{
+		{
+			name:  "noAuthNoPriv",
+			flags: gosnmp.NoAuthNoPriv,
+			user:  &gosnmp.UsmSecurityParameters{UserName: "monitor"},
+			opt:   WithV3("monitor", "", "", "", ""),
+		},
+		{
+			name:  "authNoPriv",
+			flags: gosnmp.AuthNoPriv,
+			user: &gosnmp.UsmSecurityParameters{
+				UserName:                 "monitor",
+				AuthenticationProtocol:   gosnmp.SHA,
+				AuthenticationPassphrase: "authpassword",
+			},
+			opt: WithV3("monitor", "sha", "authpassword", "", ""),
+		},
+		{
+			name:  "authPriv",
+			flags: gosnmp.AuthPriv,
+			user: &gosnmp.UsmSecurityParameters{
+				UserName:                 "monitor",
+				AuthenticationProtocol:   gosnmp.SHA,
+				AuthenticationPassphrase: "authpassword",
+				PrivacyProtocol:          gosnmp.AES,
+				PrivacyPassphrase:        "privpassword",
+			},
+			opt: WithV3("monitor", "SHA", "authpassword", "AES", "privpassword"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newAgent(t).WithV3(tt.flags, tt.user)
+			c := newTestChecker(t, a, tt.opt, WithAssertions(_passingAssertions...))
+
+			result := c.Check(testDevice(nil), false)
+			if !result.Passed {
+				t.Fatalf("expected check to pass, got error %q", result.Error)
+			}
+
+			if !strings.Contains(result.Message, "sysName=core-switch") {
+				t.Errorf("unexpected message %q", result.Message)
+			}
+		})
+	}
+}
+
+func TestCheckV3WrongPrivPassword(t *testing.T) {
+	a := newAgent(t).WithV3(gosnmp.AuthPriv, &gosnmp.UsmSecurityParameters{
+		UserName:                 "monitor",
+		AuthenticationProtocol:   gosnmp.SHA,
+		AuthenticationPassphrase: "authpassword",
+		PrivacyProtocol:          gosnmp.AES,
+		PrivacyPassphrase:        "privpassword",
+	})
+	c := newTestChecker(t, a, WithV3("monitor", "SHA", "authpassword", "AES", "wrongpassword"), WithAssertions(_passingAssertions...))
+
+	if result := c.Check(testDevice(nil), false); result.Passed {
+		t.Fatalf("expected check to fail")
+	}
+}
+
+func TestCheckOps(t *testing.T) {
+	tests := []struct {
+		op     string
+		value  string
+		passed bool
+	}{
+		{"eq", "42", true},
+		{"eq", "41", false},
+		{"ne", "41", true},
+		{"ne", "42", false},
+		{"lt", "43", true},
+		{"lt", "42", false},
+		{"le", "42", true},
+		{"le", "41", false},
+		{"gt", "41", true},
+		{"gt", "42", false},
+		{"ge", "42", true},
+		{"ge", "43", false},
+	}
+
+	a := newAgent(t)
+	c := newTestChecker(t, a)
+
+	for _, tt := range tests {
+		t.Run(tt.op+" "+tt.value, func(t *testing.T) {
+			d := testDevice(barrelman.CheckerConfig{
+				"assertions": []interface{}{
+					map[string]interface{}{"name": "temperature", "oid": _temperature, "op": tt.op, "value": tt.value},
+				},
+			})
+
+			result := c.Check(d, false)
+			if result.Passed != tt.passed {
+				t.Fatalf("got passed %t, want %t (error %q)", result.Passed, tt.passed, result.Error)
+			}
+
+			if !tt.passed && result.Event.Value != "Failed" {
+				t.Errorf("got event value %q, want Failed", result.Event.Value)
+			}
+		})
+	}
+}
+
+func TestCheckNumericOpOnString(t *testing.T) {
+	c := newTestChecker(t, newAgent(t), WithAssertions(Assertion{Name: "sysName", OID: _sysName, Op: "gt", Value: "1"}))
+
+	result := c.Check(testDevice(nil), false)
+	if result.Passed {
+		t.Fatalf("expected check to fail")
+	}
+
+	if !strings.Contains(result.Error, "expected a number") {
+		t.Errorf("unexpected error %q", result.Error)
+	}
+}
+
+func TestCheckMissingOIDs(t *testing.T) {
+	c := newTestChecker(t, newAgent(t), WithAssertions(
+		Assertion{Name: "sysName", OID: _sysName, Op: "eq", Value: "core-switch"},
+		Assertion{Name: "noObject", OID: _noObject, Op: "eq", Value: "1"},
+		Assertion{Name: "noInstance", OID: _noInstance, Op: "eq", Value: "1"},
+	))
+
+	result := c.Check(testDevice(nil), false)
+	if result.Passed {
+		t.Fatalf("expected check to fail")
+	}
+
+	for _, want := range []string{"noObject not found", "noInstance not found"} {
+		if !strings.Contains(result.Error, want) {
+			t.Errorf("expected error %q to contain %q", result.Error, want)
+		}
+	}
+
+	if result.Message != "sysName=core-switch" {
+		t.Errorf("unexpected message %q", result.Message)
+	}
+}
+
+func TestCheckNumericVersion(t *testing.T) {
+	user := &gosnmp.UsmSecurityParameters{
+		UserName:                 "monitor",
+		AuthenticationProtocol:   gosnmp.SHA,
+		AuthenticationPassphrase: "authpassword",
+	}
+
+	// YAML decodes an unquoted version as an int, and JSON as a float64
+	for _, version := range []interface{}{3, 3.0} {
+		t.Run(fmt.Sprintf("%T", version), func(t *testing.T) {
+			a := newAgent(t).WithV3(gosnmp.AuthNoPriv, user)
+			c := newTestChecker(t, a, WithAssertions(_passingAssertions...))
+
+			result := c.Check(testDevice(barrelman.CheckerConfig{
+				"version":      version,
+				"user":         "monitor",
+				"authProtocol": "sha",
+				"authPassword": "authpassword",
+			}), false)
+			if !result.Passed {
+				t.Fatalf("expected check to pass, got error %q", result.Error)
+			}
+		})
+	}
+
+	c := newTestChecker(t, newAgent(t), WithAssertions(_passingAssertions...))
+	if result := c.Check(testDevice(barrelman.CheckerConfig{"version": 2}), false); !result.Passed {
+		t.Fatalf("expected version 2 to mean 2c, got error %q", result.Error)
+	}
+}
+
+func TestCheckInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		conf barrelman.CheckerConfig
+		want string
+	}{
+		{
+			name: "not objects",
+			conf: barrelman.CheckerConfig{"assertions": []interface{}{"1.3.6.1.2.1.1.5.0"}},
+			want: "Invalid config: assertions must be objects",
+		},
+		{
+			name: "missing oid",
+			conf: barrelman.CheckerConfig{"assertions": []interface{}{
+				map[string]interface{}{"name": "sysName", "op": "eq", "value": "x"},
+			}},
+			want: `Invalid config: assertion "sysName" has no oid`,
+		},
+		{
+			name: "unknown op",
+			conf: barrelman.CheckerConfig{"assertions": []interface{}{
+				map[string]interface{}{"oid": _sysName, "op": "like", "value": "x"},
+			}},
+			want: `Invalid config: assertion 1.3.6.1.2.1.1.5.0 has unknown op "like"`,
+		},
+		{
+			name: "non-numeric threshold",
+			conf: barrelman.CheckerConfig{"assertions": []interface{}{
+				map[string]interface{}{"oid": _temperature, "op": "lt", "value": "hot"},
+			}},
+			want: "Invalid config: assertion 1.3.6.1.4.1.9.9.13.1.3.1.3.1 must have a numeric value for lt",
+		},
+		{
+			name: "unknown version",
+			conf: barrelman.CheckerConfig{"version": "1"},
+			want: `Invalid config: unsupported version "1"`,
+		},
+		{
+			name: "unknown numeric version",
+			conf: barrelman.CheckerConfig{"version": 1},
+			want: "Invalid config: unsupported version 1",
+		},
+		{
+			name: "numeric community",
+			conf: barrelman.CheckerConfig{"community": 12345},
+			want: "Invalid config: community must be strings",
+		},
+		{
+			name: "numeric v3 settings",
+			conf: barrelman.CheckerConfig{"version": "3", "user": 1234, "authPassword": 12345678.0},
+			want: "Invalid config: user, authPassword must be strings",
+		},
+	}
+
+	c := newTestChecker(t, newAgent(t), WithAssertions(_passingAssertions...))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := c.Check(testDevice(tt.conf), false)
+			if result.Passed {
+				t.Fatalf("expected check to fail")
+			}
+
+			if result.Error != tt.want {
+				t.Errorf("got error %q, want %q", result.Error, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckNoAssertions(t *testing.T) {
+	c := newTestChecker(t, newAgent(t))
+
+	result := c.Check(testDevice(nil), false)
+	if result.Passed || !result.Skipped || result.Message != "No assertions to check" || result.Event.Key != "" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+}
+
+func TestNewCheckerInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"bad assertion", []Option{WithAssertions(Assertion{OID: _sysName, Op: "??"})}},
+		{"bad auth protocol", []Option{WithV3("monitor", "sha1024", "x", "", "")}},
+		{"bad priv protocol", []Option{WithV3("monitor", "sha", "x", "rot13", "y")}},
+		{"priv without auth", []Option{WithV3("monitor", "", "", "aes", "y")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewChecker(tt.opts...); err == nil {
+				t.Fatalf("expected an error")
+			}
+		})
+	}
+}
+
+func TestCheckUnreachable(t *testing.T) {
+	// Nothing is listening on the agent's port once it's closed, so the
+	// request fails rather than hanging
+	a := newAgent(t)
+	a.Close()
+
+	c, err := NewChecker(WithPort(a.Port()), WithTimeout(1), WithRetries(0), WithAssertions(_passingAssertions...))
+	if err != nil {
+		t.Fatalf("failed to create checker: %s", err)
+	}
+
+	start := time.Now()
+	result := c.Check(testDevice(nil), false)
+	if result.Passed {
+		t.Fatalf("expected check to fail")
+	}
+
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("check took %s, expected it to fail within the timeout", elapsed)
+	}
+}
@@ -0,0 +1,21 @@
+package tcp
+
+// Option is a function which modifies a given checker, allowing the
+// user to have an option on how to setup the checker
+type Option func(*Checker)
+
+// WithPorts allows the user to set the ports that are checked on every
+// device. Devices can override these through their CheckerConfig
+func WithPorts(ports ...int) Option {
+	return func(c *Checker) {
+		c.ports = ports
+	}
+}
+
+// WithTimeout allows the user to set the timeout (in seconds) of each
+// connection attempt
+func WithTimeout(t int) Option {
+	return func(c *Checker) {
+		c.timeout = t
+	}
+}
@@ -0,0 +1,140 @@
+package tcp
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/byuoitav/barrelman"
+)
+
+// ConfigKey is the name of the device CheckerConfig read by the checker.
+// The ports and timeout options override the checker's own settings for the
+// device
+const ConfigKey = "tcp"
+
+// Checker attempts to open a TCP connection to one or more ports on the
+// device to check that the services on those ports are accepting connections
+type Checker struct {
+	ports   []int
+	timeout int
+}
+
+// portResult is the outcome of a connection attempt to a single port
+type portResult struct {
+	port    int
+	latency time.Duration
+	err     error
+}
+
+// NewChecker returns a tcp checker with the given options set
+func NewChecker(opts ...Option) (*Checker, error) {
+	c := Checker{
+		timeout: 5,
+	}
+
+	// Apply options
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return &c, nil
+}
+
+// Check attempts to connect to each of the configured ports on the device.
+// If every connection succeeds then the check is considered healthy. Any
+// failed connection will result in an unhealthy check. Devices with no
+// configured ports are skipped
+func (c *Checker) Check(d *barrelman.Device, forceRecheck bool) barrelman.CheckResult {
+	result := barrelman.CheckResult{
+		RunTime: time.Now(),
+		Passed:  true,
+		Event: barrelman.Event{
+			Device: d,
+			Key:    "reachable",
+			Value:  "Reachable",
+		},
+	}
+
+	// Apply any device specific settings
+	ports, timeout := c.ports, c.timeout
+	conf := d.Config(ConfigKey)
+	if p, ok := conf.Ints("ports"); ok {
+		ports = p
+	}
+	if i, ok := conf.Int("timeout"); ok && i > 0 {
+		timeout = i
+	}
+
+	if len(ports) == 0 {
+		return barrelman.CheckResult{
+			RunTime: time.Now(),
+			Skipped: true,
+			Message: "No ports to check",
+		}
+	}
+
+	// Try all of the ports at once
+	results := make([]portResult, len(ports))
+	var wg sync.WaitGroup
+	for i, port := range ports {
+		wg.Add(1)
+		go func(i, port int) {
+			defer wg.Done()
+			results[i] = dial(d.Address, port, time.Duration(timeout)*time.Second)
+		}(i, port)
+	}
+	wg.Wait()
+
+	connected := []string{}
+	failed := []string{}
+	result.Metrics = make(map[string]barrelman.Metric, len(results))
+	for _, r := range results {
+		if r.err != nil {
+			failed = append(failed, fmt.Sprintf("%d (%s)", r.port, r.err))
+			continue
+		}
+
+		result.Metrics[fmt.Sprintf("connect-time-%d", r.port)] = barrelman.Metric{
+			Value: float64(r.latency/time.Nanosecond) / 1000000,
+			Unit:  "ms",
+		}
+
+		connected = append(connected, fmt.Sprintf(
+			"%d in %fms",
+			r.port,
+			float64(r.latency/time.Nanosecond)/1000000, // Getting ms down to several decimal places
+		))
+	}
+
+	result.Metrics["ports-open"] = barrelman.Metric{Value: float64(len(connected))}
+
+	if len(connected) > 0 {
+		result.Message = "Connected to " + strings.Join(connected, ", ")
+	}
+
+	if len(failed) > 0 {
+		result.Passed = false
+		result.Error = "Failed to connect to " + strings.Join(failed, ", ")
+		result.Event.Value = "Unreachable"
+	}
+
+	return result
+}
+
+// dial opens and immediately closes a connection to the given port
+func dial(address string, port int, timeout time.Duration) portResult {
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(address, strconv.Itoa(port)), timeout)
+	if err != nil {
+		return portResult{port: port, err: err}
+	}
+
+	latency := time.Since(start)
+	conn.Close()
+
+	return portResult{port: port, latency: latency}
+}
@@ -0,0 +1,82 @@
+package tcp
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/byuoitav/barrelman"
+)
+
+// listen returns the port of a loopback listener, and the port of one that
+// has already been closed
+func listen(t *testing.T) (int, int) {
+	t.Helper()
+
+	open, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	t.Cleanup(func() { open.Close() })
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	closed.Close()
+
+	return open.Addr().(*net.TCPAddr).Port, closed.Addr().(*net.TCPAddr).Port
+}
+
+func TestCheck(t *testing.T) {
+	open, closed := listen(t)
+
+	tests := []struct {
+		name    string
+		ports   []int
+		passed  bool
+		skipped bool
+		value   string
+	}{
+		{name: "open", ports: []int{open}, passed: true, value: "Reachable"},
+		{name: "closed", ports: []int{open, closed}, value: "Unreachable"},
+		{name: "no ports", skipped: true},
+	}
+
+	c, err := NewChecker(WithTimeout(1))
+	if err != nil {
+		t.Fatalf("failed to create checker: %s", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ports := make([]interface{}, len(tt.ports))
+			for i, p := range tt.ports {
+				ports[i] = p
+			}
+
+			d := &barrelman.Device{
+				Name:          "ITB-1101-CP1",
+				Address:       "127.0.0.1",
+				CheckerConfig: map[string]barrelman.CheckerConfig{ConfigKey: {"ports": ports}},
+			}
+
+			result := c.Check(d, false)
+			if result.Passed != tt.passed || result.Skipped != tt.skipped {
+				t.Fatalf("got passed %t skipped %t, want %t and %t (error %q)", result.Passed, result.Skipped, tt.passed, tt.skipped, result.Error)
+			}
+
+			if result.Event.Value != tt.value {
+				t.Errorf("got event value %q, want %q", result.Event.Value, tt.value)
+			}
+
+			if tt.skipped && result.Event.Key != "" {
+				t.Errorf("expected no event for a skipped check, got %+v", result.Event)
+			}
+
+			if !tt.passed && !tt.skipped && !strings.HasPrefix(result.Error, "Failed to connect to ") {
+				t.Errorf("unexpected error %q", result.Error)
+			}
+		})
+	}
+}
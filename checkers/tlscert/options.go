@@ -0,0 +1,39 @@
+package tlscert
+
+// Option is a function which modifies a given checker, allowing the
+// user to have an option on how to setup the checker
+type Option func(*Checker)
+
+// WithPort allows the user to set the port the TLS connection is made to.
+// The default is 443
+func WithPort(p int) Option {
+	return func(c *Checker) {
+		c.port = p
+	}
+}
+
+// WithTimeout allows the user to set the timeout (in seconds) of the TLS
+// connection. The default is 10 seconds
+func WithTimeout(t int) Option {
+	return func(c *Checker) {
+		c.timeout = t
+	}
+}
+
+// WithExpiryWindow allows the user to set how many days before a
+// certificate expires the check should start failing. The default is 30 days
+func WithExpiryWindow(days int) Option {
+	return func(c *Checker) {
+		c.expiryWindow = days
+	}
+}
+
+// WithAllowedSelfSigned allows the user to whitelist self-signed or
+// otherwise untrusted certificates by their SHA-256 fingerprints, given as hex
+// strings with or without colons. A chain is allowed if any certificate in
+// it is whitelisted, so a private CA can be allowed by its own fingerprint
+func WithAllowedSelfSigned(fingerprints ...string) Option {
+	return func(c *Checker) {
+		c.allowed = append(c.allowed, fingerprints...)
+	}
+}
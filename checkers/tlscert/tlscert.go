@@ -0,0 +1,223 @@
+package tlscert
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/byuoitav/barrelman"
+)
+
+// ConfigKey is the name of the device CheckerConfig read by the checker.
+// The port, timeout, expiryWindow, and allowedSelfSigned options override the
+// checker's own settings for the device, and the serverName option sets the
+// name the certificate is expected to be valid for, which defaults to the
+// device's address
+const ConfigKey = "tlscert"
+
+// Checker connects to the device over TLS and inspects the certificate chain
+// that it presents
+type Checker struct {
+	port         int
+	timeout      int
+	expiryWindow int
+	allowed      []string
+}
+
+// NewChecker returns a TLS certificate checker with the given options set
+func NewChecker(opts ...Option) (*Checker, error) {
+	c := Checker{
+		port:         443,
+		timeout:      10,
+		expiryWindow: 30,
+	}
+
+	// Apply options
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return &c, nil
+}
+
+// Check connects to the given device and inspects its certificate chain. The
+// check fails if any certificate in the chain expires within the expiry
+// window or has already expired, if the certificate isn't valid for the
+// device's name, or if the chain isn't trusted by the system roots and hasn't
+// been whitelisted. The event value is the number of days until the first
+// certificate in the chain expires
+func (c *Checker) Check(d *barrelman.Device, forceRecheck bool) barrelman.CheckResult {
+	result := barrelman.CheckResult{
+		RunTime: time.Now(),
+		Passed:  true,
+		Event: barrelman.Event{
+			Device: d,
+			Key:    "cert-days-remaining",
+		},
+	}
+
+	// Apply any device specific settings
+	port, timeout, window, allowed := c.port, c.timeout, c.expiryWindow, c.allowed
+	serverName := d.Address
+	conf := d.Config(ConfigKey)
+	if p, ok := conf.Int("port"); ok && p > 0 {
+		port = p
+	}
+	if t, ok := conf.Int("timeout"); ok && t > 0 {
+		timeout = t
+	}
+	if w, ok := conf.Int("expiryWindow"); ok {
+		window = w
+	}
+	if a, ok := conf.Strings("allowedSelfSigned"); ok {
+		allowed = a
+	}
+	if n, ok := conf.String("serverName"); ok && n != "" {
+		serverName = n
+	}
+
+	// Verification is done below so that each problem can be reported
+	dialer := &net.Dialer{Timeout: time.Duration(timeout) * time.Second}
+	conn, err := tls.DialWithDialer(dialer, "tcp", net.JoinHostPort(d.Address, strconv.Itoa(port)), &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		result.Passed = false
+		result.Error = fmt.Sprintf("Failed to connect: %s", err)
+		result.Event.Value = "Unknown"
+		return result
+	}
+	chain := conn.ConnectionState().PeerCertificates
+	conn.Close()
+
+	if len(chain) == 0 {
+		result.Passed = false
+		result.Error = "No certificates presented"
+		result.Event.Value = "Unknown"
+		return result
+	}
+
+	// Find the certificate that expires first
+	leaf := chain[0]
+	first := leaf
+	for _, cert := range chain[1:] {
+		if cert.NotAfter.Before(first.NotAfter) {
+			first = cert
+		}
+	}
+
+	now := time.Now()
+	days := int(math.Floor(first.NotAfter.Sub(now).Hours() / 24))
+	result.Event.Value = strconv.Itoa(days)
+	result.Metrics = map[string]barrelman.Metric{
+		"cert-days-remaining": {Value: float64(days), Unit: "days"},
+	}
+	result.Message = fmt.Sprintf("Certificate %q expires in %d days (%s)", name(first), days, first.NotAfter.Format(time.RFC3339))
+
+	errs := []string{}
+	switch {
+	case now.After(first.NotAfter):
+		errs = append(errs, fmt.Sprintf("certificate %q expired on %s", name(first), first.NotAfter.Format(time.RFC3339)))
+	case first.NotAfter.Before(now.AddDate(0, 0, window)):
+		errs = append(errs, fmt.Sprintf("certificate %q expires in %d days, within %d day window", name(first), days, window))
+	}
+
+	if err := leaf.VerifyHostname(serverName); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	if err := untrusted(chain); err != nil && !whitelisted(chain, allowed) {
+		errs = append(errs, err.Error())
+	}
+
+	if len(errs) > 0 {
+		result.Passed = false
+		result.Error = strings.Join(errs, "; ")
+	}
+
+	return result
+}
+
+// untrusted returns why the chain isn't trusted by the system roots, or nil if
+// it is. Expiry and the hostname are checked separately, so neither is
+// reported here
+func untrusted(chain []*x509.Certificate) error {
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	// Verify at a time when every certificate is valid so that expiry, which
+	// is reported separately, doesn't hide the chain's trust
+	at := chain[0].NotBefore
+	for _, cert := range chain {
+		if cert.NotBefore.After(at) {
+			at = cert.NotBefore
+		}
+	}
+
+	_, err := chain[0].Verify(x509.VerifyOptions{
+		Intermediates: intermediates,
+		CurrentTime:   at,
+	})
+
+	var invalid x509.CertificateInvalidError
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &invalid) && invalid.Reason == x509.Expired:
+		// The chain's validity periods don't overlap, which is reported as
+		// an expired certificate
+		return nil
+	}
+
+	root := chain[len(chain)-1]
+	if bytes.Equal(root.RawIssuer, root.RawSubject) && root.CheckSignature(root.SignatureAlgorithm, root.RawTBSCertificate, root.Signature) == nil {
+		return fmt.Errorf("chain is self-signed by %q (sha256 %s)", name(root), fingerprint(root))
+	}
+
+	return fmt.Errorf("chain is untrusted: %s", err)
+}
+
+// whitelisted returns true if any certificate in the chain has one of the
+// allowed fingerprints
+func whitelisted(chain []*x509.Certificate, allowed []string) bool {
+	for _, cert := range chain {
+		fp := fingerprint(cert)
+		for _, a := range allowed {
+			if strings.EqualFold(strings.ReplaceAll(a, ":", ""), fp) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// fingerprint returns the hex encoded SHA-256 fingerprint of the certificate
+func fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// name returns a human readable name for the certificate
+func name(cert *x509.Certificate) string {
+	switch {
+	case cert.Subject.CommonName != "":
+		return cert.Subject.CommonName
+	case len(cert.DNSNames) > 0:
+		return cert.DNSNames[0]
+	}
+
+	return cert.Subject.String()
+}
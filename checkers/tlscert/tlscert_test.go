@@ -0,0 +1,224 @@
+package tlscert
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/byuoitav/barrelman"
+)
+
+// issue creates a certificate for cn valid between notBefore and notAfter. It
+// is signed by parent, or is self-signed if parent is nil
+func issue(t *testing.T, cn string, ca bool, notBefore, notAfter time.Time, parent *x509.Certificate, parentKey crypto.Signer) (*x509.Certificate, crypto.Signer) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %s", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatalf("failed to generate serial: %s", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		BasicConstraintsValid: true,
+		IsCA:                  ca,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if !ca {
+		tmpl.IPAddresses = []net.IP{net.IPv4(127, 0, 0, 1)}
+	}
+
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, key.Public(), parentKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %s", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %s", err)
+	}
+
+	return cert, key
+}
+
+// serve presents the given chain on a loopback port and returns the port
+func serve(t *testing.T, key crypto.Signer, chain ...*x509.Certificate) int {
+	t.Helper()
+
+	cert := tls.Certificate{PrivateKey: key}
+	for _, c := range chain {
+		cert.Certificate = append(cert.Certificate, c.Raw)
+	}
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				conn.(*tls.Conn).Handshake()
+				conn.Close()
+			}()
+		}
+	}()
+
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func check(t *testing.T, port int, opts ...Option) barrelman.CheckResult {
+	t.Helper()
+
+	c, err := NewChecker(append([]Option{WithPort(port), WithTimeout(1)}, opts...)...)
+	if err != nil {
+		t.Fatalf("failed to create checker: %s", err)
+	}
+
+	return c.Check(&barrelman.Device{Name: "ITB-1101-CP1", Address: "127.0.0.1"}, false)
+}
+
+func TestCheckExpiry(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		notAfter time.Time
+		window   int
+		passed   bool
+		days     string
+		err      string
+	}{
+		{
+			name:     "valid",
+			notAfter: now.Add(60 * 24 * time.Hour),
+			window:   30,
+			passed:   true,
+			days:     "59",
+		},
+		{
+			name:     "within window",
+			notAfter: now.Add(10 * 24 * time.Hour),
+			window:   30,
+			days:     "9",
+			err:      "expires in 9 days, within 30 day window",
+		},
+		{
+			name:     "expired within the last day",
+			notAfter: now.Add(-time.Hour),
+			window:   0,
+			days:     "-1",
+			err:      "expired on",
+		},
+		{
+			name:     "expires today",
+			notAfter: now.Add(time.Hour),
+			window:   1,
+			days:     "0",
+			err:      "expires in 0 days, within 1 day window",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, key := issue(t, "ITB-1101-CP1", false, now.Add(-48*time.Hour), tt.notAfter, nil, nil)
+
+			result := check(t, serve(t, key, cert), WithExpiryWindow(tt.window), WithAllowedSelfSigned(fingerprint(cert)))
+			if result.Passed != tt.passed {
+				t.Fatalf("got passed %t, want %t (error %q)", result.Passed, tt.passed, result.Error)
+			}
+
+			if result.Event.Value != tt.days {
+				t.Errorf("got %s days remaining, want %s", result.Event.Value, tt.days)
+			}
+
+			if !strings.Contains(result.Error, tt.err) {
+				t.Errorf("got error %q, want it to contain %q", result.Error, tt.err)
+			}
+		})
+	}
+}
+
+func TestCheckTrust(t *testing.T) {
+	now := time.Now()
+	notBefore, notAfter := now.Add(-time.Hour), now.Add(365*24*time.Hour)
+
+	root, rootKey := issue(t, "Private Root CA", true, notBefore, notAfter, nil, nil)
+	inter, interKey := issue(t, "Private Issuing CA", true, notBefore, notAfter, root, rootKey)
+	leaf, leafKey := issue(t, "ITB-1101-CP1", false, notBefore, notAfter, inter, interKey)
+	selfSigned, selfSignedKey := issue(t, "ITB-1101-CP1", false, notBefore, notAfter, nil, nil)
+
+	tests := []struct {
+		name    string
+		port    int
+		allowed []string
+		err     string
+	}{
+		{
+			name: "self-signed",
+			port: serve(t, selfSignedKey, selfSigned),
+			err:  "chain is self-signed by \"ITB-1101-CP1\" (sha256 " + fingerprint(selfSigned) + ")",
+		},
+		{
+			name: "private CA without root",
+			port: serve(t, leafKey, leaf, inter),
+			err:  "chain is untrusted: x509: certificate signed by unknown authority",
+		},
+		{
+			name: "private CA with root",
+			port: serve(t, leafKey, leaf, inter, root),
+			err:  "chain is self-signed by \"Private Root CA\"",
+		},
+		{
+			name:    "whitelisted private CA",
+			port:    serve(t, leafKey, leaf, inter),
+			allowed: []string{fingerprint(inter)},
+		},
+		{
+			name:    "whitelisted self-signed",
+			port:    serve(t, selfSignedKey, selfSigned),
+			allowed: []string{fingerprint(selfSigned)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := check(t, tt.port, WithAllowedSelfSigned(tt.allowed...))
+			if result.Passed != (tt.err == "") {
+				t.Fatalf("got passed %t, error %q", result.Passed, result.Error)
+			}
+
+			if !strings.HasPrefix(result.Error, tt.err) {
+				t.Errorf("got error %q, want %q", result.Error, tt.err)
+			}
+		})
+	}
+}
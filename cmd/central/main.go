@@ -1,38 +1,79 @@
 package main
 
 import (
+	"context"
 	"log"
-	"sync"
+	"net/http"
+	"time"
 
+	"github.com/byuoitav/barrelman"
+	"github.com/byuoitav/barrelman/api"
+	"github.com/byuoitav/barrelman/cachestore"
+	"github.com/byuoitav/barrelman/checkers/dns"
 	"github.com/byuoitav/barrelman/checkers/ping"
+	"github.com/byuoitav/barrelman/checkers/serial"
+	"github.com/byuoitav/barrelman/checkers/tcp"
 	"github.com/byuoitav/barrelman/couch"
+	"github.com/byuoitav/barrelman/filestore"
 	"github.com/byuoitav/barrelman/monitors/intervalmonitor"
+	"github.com/byuoitav/barrelman/monitors/roommonitor"
 	"github.com/spf13/pflag"
 )
 
+// deviceStore is a DeviceStore which can also be watched for changes
+type deviceStore interface {
+	barrelman.DeviceStore
+	barrelman.DeviceWatcher
+}
+
 func main() {
 	var (
+		deviceFile   string
+		deviceCache  string
 		dbAddr       string
 		dbUser       string
 		dbPass       string
 		eventHubAddr string
+		listenAddr   string
 	)
 
+	pflag.StringVar(&deviceFile, "device-file", "", "A YAML or JSON file to read devices from instead of the couch database")
+	pflag.StringVar(&deviceCache, "device-cache", "", "A file in which to cache devices for when the store is unreachable, such as one on a mounted volume. Disabled if empty")
 	pflag.StringVar(&dbAddr, "db-address", "", "The address to the couch database")
 	pflag.StringVar(&dbUser, "db-username", "", "The username for the couch database")
 	pflag.StringVar(&dbPass, "db-password", "", "The password for the couch database")
 	pflag.StringVar(&eventHubAddr, "eventhub-address", "", "The address for the event hub")
+	pflag.StringVar(&listenAddr, "listen-address", ":8080", "The address on which to serve the status API")
 
 	pflag.Parse()
 
-	c, err := couch.New(dbAddr, dbUser, dbPass)
-	if err != nil {
-		log.Panicf("Failed to initialize couch: %s", err)
+	var store deviceStore
+	var err error
+	if deviceFile != "" {
+		store, err = filestore.New(deviceFile)
+		if err != nil {
+			log.Panicf("Failed to initialize device file: %s", err)
+		}
+	} else {
+		store, err = couch.New(dbAddr, dbUser, dbPass)
+		if err != nil {
+			log.Panicf("Failed to initialize couch: %s", err)
+		}
 	}
 
-	devs, err := c.GetAllCentralMonitoringDevices()
+	// Serve devices from disk when the store is unreachable
+	if deviceCache != "" {
+		store, err = cachestore.New(store, deviceCache)
+		if err != nil {
+			log.Panicf("Failed to initialize device cache: %s", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	devs, err := store.GetAllCentralMonitoringDevicesContext(ctx)
+	cancel()
 	if err != nil {
-		log.Panicf("Failed to get receivers from database: %s", err)
+		log.Panicf("Failed to get receivers from store: %s", err)
 	}
 
 	m, err := intervalmonitor.NewMonitor()
@@ -40,12 +81,34 @@ func main() {
 		log.Panicf("Failed to create interval monitor: %s", err)
 	}
 
-	pingChecker, err := ping.NewChecker()
+	// Share one ICMP socket between all of the devices
+	pingChecker, err := ping.NewChecker(ping.WithSharedSocket(true))
 	if err != nil {
 		log.Panicf("Failed to initialize ping checker: %s", err)
 	}
 
+	// Pinned addresses are configured per device
+	dnsChecker, err := dns.NewChecker()
+	if err != nil {
+		log.Panicf("Failed to initialize dns checker: %s", err)
+	}
+
+	// Ports to check are configured per device
+	tcpChecker, err := tcp.NewChecker()
+	if err != nil {
+		log.Panicf("Failed to initialize tcp checker: %s", err)
+	}
+
+	// Expected identities are configured per device
+	serialChecker, err := serial.NewChecker()
+	if err != nil {
+		log.Panicf("Failed to initialize serial checker: %s", err)
+	}
+
 	m.RegisterChecker("ping", 120, pingChecker)
+	m.RegisterChecker("dns", 300, dnsChecker)
+	m.RegisterChecker("tcp", 120, tcpChecker)
+	m.RegisterChecker("serial", 600, serialChecker)
 
 	log.Printf("Beginning monitoring...")
 
@@ -56,8 +119,25 @@ func main() {
 
 	log.Printf("Monitoring initialized on %d devices", len(devs))
 
-	// Hang forever
-	var wg sync.WaitGroup
-	wg.Add(1)
-	wg.Wait()
+	// Keep the monitored devices in sync with the database
+	changes, err := store.WatchCentralMonitoringDevices(context.Background())
+	if err != nil {
+		log.Printf("Failed to watch for device changes: %s", err)
+	} else {
+		go barrelman.SyncDevices(m, changes)
+	}
+
+	rm, err := roommonitor.NewMonitor(m)
+	if err != nil {
+		log.Panicf("Failed to create room monitor: %s", err)
+	}
+
+	server, err := api.NewServer(m, api.WithRoomMonitor(rm))
+	if err != nil {
+		log.Panicf("Failed to create api server: %s", err)
+	}
+
+	// Serve the status api forever
+	log.Printf("Serving status api on %s", listenAddr)
+	log.Panicf("Failed to serve status api: %s", http.ListenAndServe(listenAddr, server))
 }
@@ -1,35 +1,60 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net/http"
 	"strings"
-	"sync"
+	"time"
 
+	"github.com/byuoitav/barrelman"
+	"github.com/byuoitav/barrelman/api"
 	"github.com/byuoitav/barrelman/avevent"
+	"github.com/byuoitav/barrelman/cachestore"
+	"github.com/byuoitav/barrelman/checkers/composite"
 	"github.com/byuoitav/barrelman/checkers/health"
 	"github.com/byuoitav/barrelman/checkers/ping"
 	"github.com/byuoitav/barrelman/couch"
+	"github.com/byuoitav/barrelman/emitters/changeemitter"
+	"github.com/byuoitav/barrelman/filestore"
 	"github.com/byuoitav/barrelman/monitors/intervalmonitor"
+	"github.com/byuoitav/barrelman/monitors/roommonitor"
 	"github.com/spf13/pflag"
 )
 
+// deviceStore is a DeviceStore which can also be watched for changes
+type deviceStore interface {
+	barrelman.DeviceStore
+	barrelman.DeviceWatcher
+}
+
 func main() {
 	var (
+		deviceFile   string
+		deviceCache  string
 		dbAddr       string
 		dbUser       string
 		dbPass       string
 		eventHubAddr string
 		avAPIAddr    string
 		systemID     string
+		reportInt    int
+		listenAddr   string
+		heartbeat    int
 	)
 
+	pflag.StringVar(&deviceFile, "device-file", "", "A YAML or JSON file to read devices from instead of the couch database")
+	pflag.StringVar(&deviceCache, "device-cache", "", "A file in which to cache devices for when the store is unreachable, such as one on a mounted volume. Disabled if empty")
 	pflag.StringVar(&dbAddr, "db-address", "", "The address to the couch database")
 	pflag.StringVar(&dbUser, "db-username", "", "The username for the couch database")
 	pflag.StringVar(&dbPass, "db-password", "", "The password for the couch database")
 	pflag.StringVar(&eventHubAddr, "eventhub-address", "", "The address for the event hub")
 	pflag.StringVar(&avAPIAddr, "av-api-address", "", "The address for the av api")
 	pflag.StringVar(&systemID, "system-id", "", "The ID of this system")
+	pflag.StringVar(&listenAddr, "listen-address", ":8080", "The address on which to serve the status API")
+	pflag.IntVar(&heartbeat, "event-heartbeat", 900, "The interval (in seconds) on which unchanged events are emitted again, 0 to disable")
+	pflag.IntVar(&reportInt, "room-report-interval", 60, "The interval (in seconds) on which to report the status of the room")
 
 	pflag.Parse()
 
@@ -40,21 +65,51 @@ func main() {
 
 	roomID := fmt.Sprintf("%s-%s", systemParts[0], systemParts[1])
 
-	c, err := couch.New(dbAddr, dbUser, dbPass)
-	if err != nil {
-		log.Panicf("Failed to initialize couch: %s", err)
+	var store deviceStore
+	var err error
+	if deviceFile != "" {
+		store, err = filestore.New(deviceFile)
+		if err != nil {
+			log.Panicf("Failed to initialize device file: %s", err)
+		}
+	} else {
+		store, err = couch.New(dbAddr, dbUser, dbPass)
+		if err != nil {
+			log.Panicf("Failed to initialize couch: %s", err)
+		}
 	}
 
-	devs, err := c.GetRoomDevices(roomID)
-	if err != nil {
-		log.Panicf("Failed to get devices from database: %s", err)
+	// Serve devices from disk when the store is unreachable
+	if deviceCache != "" {
+		store, err = cachestore.New(store, deviceCache)
+		if err != nil {
+			log.Panicf("Failed to initialize device cache: %s", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	devs, err := store.GetRoomDevicesContext(ctx, roomID)
+	cancel()
+	switch {
+	case err != nil && deviceCache == "":
+		log.Panicf("Failed to get devices from store: %s", err)
+	case err != nil:
+		// Nothing has been cached yet. The device cache retries watching the
+		// store and sends every device once it can be reached
+		log.Printf("Failed to get devices from store, starting with none: %s", err)
 	}
 
-	e, err := avevent.NewLogEmitter(eventHubAddr, systemID)
+	logEmitter, err := avevent.NewLogEmitter(eventHubAddr, systemID)
 	if err != nil {
 		log.Panicf("Failed to start event emitter: %s", err)
 	}
 
+	// Only emit events when their values change
+	e, err := changeemitter.NewEmitter(logEmitter, changeemitter.WithHeartbeat(heartbeat))
+	if err != nil {
+		log.Panicf("Failed to start change emitter: %s", err)
+	}
+
 	m, err := intervalmonitor.NewMonitor(intervalmonitor.WithEventEmitter(e), intervalmonitor.WithJitter(5))
 	if err != nil {
 		log.Panicf("Failed to create interval monitor: %s", err)
@@ -70,20 +125,68 @@ func main() {
 		log.Panicf("Failed to initialize health checker: %s", err)
 	}
 
+	// Don't bother checking the health of devices that are offline
+	dependentHealthChecker, err := composite.NewChecker(
+		composite.WithChecker("health", healthChecker),
+		composite.WithDependencies(m, "ping"),
+	)
+	if err != nil {
+		log.Panicf("Failed to initialize dependent health checker: %s", err)
+	}
+
 	m.RegisterChecker("ping", 120, pingChecker)
-	m.RegisterChecker("health", 60, healthChecker)
+	m.RegisterChecker("health", 60, dependentHealthChecker)
 
 	log.Printf("Beginning monitoring...")
 
 	// Initialize monitoring
 	for _, d := range devs {
-		m.RegisterDevice(&d)
+		m.RegisterDevice(d)
 	}
 
 	log.Printf("Monitoring initialized on %d devices", len(devs))
 
-	// Hang forever
-	var wg sync.WaitGroup
-	wg.Add(1)
-	wg.Wait()
+	// Keep the monitored devices in sync with the database, retrying until the
+	// store can be watched
+	go func() {
+		for {
+			changes, err := store.WatchRoomDevices(context.Background(), roomID)
+			if err == nil {
+				barrelman.SyncDevices(m, changes)
+				return
+			}
+
+			log.Printf("Failed to watch for device changes, retrying: %s", err)
+			time.Sleep(30 * time.Second)
+		}
+	}()
+
+	rm, err := roommonitor.NewMonitor(m)
+	if err != nil {
+		log.Panicf("Failed to create room monitor: %s", err)
+	}
+
+	server, err := api.NewServer(m, api.WithRoomMonitor(rm))
+	if err != nil {
+		log.Panicf("Failed to create api server: %s", err)
+	}
+
+	go func() {
+		log.Printf("Serving status api on %s", listenAddr)
+		log.Panicf("Failed to serve status api: %s", http.ListenAndServe(listenAddr, server))
+	}()
+
+	// Report the status of the room forever
+	for range time.Tick(time.Duration(reportInt) * time.Second) {
+		status, err := rm.RoomStatus(roomID)
+		if err != nil {
+			log.Printf("Failed to get room status: %s", err)
+			continue
+		}
+
+		log.Printf("Room %s is %s (%d of %d devices healthy)", roomID, status.State, status.HealthyCount, status.DeviceCount)
+		for checker, devs := range status.FailingCheckers {
+			log.Printf("Checker %s failing on: %s", checker, strings.Join(devs, ", "))
+		}
+	}
 }
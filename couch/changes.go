@@ -0,0 +1,173 @@
+package couch
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/byuoitav/barrelman"
+	"github.com/go-kivik/kivik/v3"
+)
+
+// _retryInterval is how long to wait before reconnecting to a changes feed
+// that has failed
+const _retryInterval = 10 * time.Second
+
+// _heartbeat is how often (in milliseconds) couch should send a heartbeat on
+// an idle changes feed so that a dead connection can be detected
+const _heartbeat = 30000
+
+// WatchRoomDevices follows the changes feed of the devices database and sends
+// a DeviceChange on the returned channel each time a device in the given room
+// is added, updated, or removed. Devices which move out of the room are
+// reported as removed. The channel is closed when the context is canceled
+func (s *Service) WatchRoomDevices(ctx context.Context, roomID string) (<-chan barrelman.DeviceChange, error) {
+	// Follow the feed from before the current devices are fetched, so that
+	// nothing that changes in between is missed
+	since, err := s.updateSeq(ctx, _devicesDB)
+	if err != nil {
+		return nil, err
+	}
+
+	devs, err := s.GetRoomDevicesContext(ctx, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("get current devices: %w", err)
+	}
+
+	known := make(map[string]bool, len(devs))
+	for _, d := range devs {
+		known[d.Name] = true
+	}
+
+	changes := make(chan barrelman.DeviceChange)
+	go s.followChanges(ctx, _devicesDB, since, changes, func(feed *kivik.Changes) {
+		id := feed.ID()
+
+		if feed.Deleted() {
+			if known[id] {
+				delete(known, id)
+				changes <- removed(id)
+			}
+			return
+		}
+
+		d := device{}
+		if err := feed.ScanDoc(&d); err != nil {
+			log.Printf("Failed to parse device %s from changes feed: %s", id, err)
+			return
+		}
+
+		switch {
+		case d.Room == roomID && known[id]:
+			changes <- barrelman.DeviceChange{Type: barrelman.DeviceUpdated, Device: convertDevice(d)}
+		case d.Room == roomID:
+			known[id] = true
+			changes <- barrelman.DeviceChange{Type: barrelman.DeviceAdded, Device: convertDevice(d)}
+		case known[id]:
+			delete(known, id)
+			changes <- removed(id)
+		}
+	})
+
+	return changes, nil
+}
+
+// WatchCentralMonitoringDevices follows the changes feed of the central
+// monitoring database and sends a DeviceChange on the returned channel for
+// each device that is added, updated, or removed from the central monitoring
+// document. The channel is closed when the context is canceled
+func (s *Service) WatchCentralMonitoringDevices(ctx context.Context) (<-chan barrelman.DeviceChange, error) {
+	since, err := s.updateSeq(ctx, _centralMonitoringDB)
+	if err != nil {
+		return nil, err
+	}
+
+	devs, err := s.GetAllCentralMonitoringDevicesContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get current devices: %w", err)
+	}
+
+	changes := make(chan barrelman.DeviceChange)
+	go s.followChanges(ctx, _centralMonitoringDB, since, changes, func(feed *kivik.Changes) {
+		if feed.ID() != "default" {
+			return
+		}
+
+		doc := centralDevicesDoc{}
+		if !feed.Deleted() {
+			if err := feed.ScanDoc(&doc); err != nil {
+				log.Printf("Failed to parse central monitoring doc from changes feed: %s", err)
+				return
+			}
+		}
+
+		current := []*barrelman.Device{}
+		for _, d := range doc.Devices {
+			current = append(current, convertCentralDevice(d))
+		}
+
+		for _, c := range barrelman.DiffDevices(devs, current) {
+			changes <- c
+		}
+
+		devs = current
+	})
+
+	return changes, nil
+}
+
+// updateSeq returns the current update sequence of the given database
+func (s *Service) updateSeq(ctx context.Context, dbName string) (string, error) {
+	stats, err := s.client.DB(ctx, dbName).Stats(ctx)
+	if err != nil {
+		return "", fmt.Errorf("get %s update sequence: %w", dbName, err)
+	}
+
+	return stats.UpdateSeq, nil
+}
+
+// followChanges calls handle for each change in the given database's changes
+// feed after the given sequence, until the context is canceled. The feed is
+// reconnected from the last seen sequence if it fails, so changes made while
+// it was down are still handled. The changes channel is closed once the feed
+// is done
+func (s *Service) followChanges(ctx context.Context, dbName, since string, changes chan barrelman.DeviceChange, handle func(*kivik.Changes)) {
+	defer close(changes)
+
+	for {
+		feed, err := s.client.DB(ctx, dbName).Changes(ctx, kivik.Options{
+			"feed":         "continuous",
+			"since":        since,
+			"include_docs": true,
+			"heartbeat":    _heartbeat,
+		})
+		if err == nil {
+			for feed.Next() {
+				since = feed.Seq()
+				handle(feed)
+			}
+
+			err = feed.Err()
+			if last := feed.LastSeq(); last != "" {
+				since = last
+			}
+			feed.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(_retryInterval):
+		}
+
+		log.Printf("Reconnecting to %s changes feed after error: %v", dbName, err)
+	}
+}
+
+func removed(name string) barrelman.DeviceChange {
+	return barrelman.DeviceChange{
+		Type:   barrelman.DeviceRemoved,
+		Device: &barrelman.Device{Name: name},
+	}
+}
@@ -4,10 +4,15 @@ import (
 	"fmt"
 	"net/url"
 
+	"github.com/byuoitav/barrelman"
 	_ "github.com/go-kivik/couchdb/v3"
 	"github.com/go-kivik/kivik/v3"
 )
 
+var _ barrelman.DeviceStore = (*Service)(nil)
+var _ barrelman.DeviceWatcher = (*Service)(nil)
+
+// Service is a DeviceStore backed by couch
 type Service struct {
 	client *kivik.Client
 }
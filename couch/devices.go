@@ -29,24 +29,36 @@ type device struct {
 	Room    string `json:"room"`
 }
 
-func (s *Service) GetDevice(id string) (barrelman.Device, error) {
-	db := s.client.DB(context.TODO(), _devicesDB)
+// GetDevice returns the device with the given ID
+func (s *Service) GetDevice(id string) (*barrelman.Device, error) {
+	return s.GetDeviceContext(context.Background(), id)
+}
+
+// GetDeviceContext returns the device with the given ID
+func (s *Service) GetDeviceContext(ctx context.Context, id string) (*barrelman.Device, error) {
+	db := s.client.DB(ctx, _devicesDB)
 	dev := device{}
-	err := db.Get(context.TODO(), id).ScanDoc(&dev)
+	err := db.Get(ctx, id).ScanDoc(&dev)
 	if err != nil {
 		// Not found error
 		if kivik.StatusCode(err) == http.StatusNotFound {
-			return barrelman.Device{}, fmt.Errorf("Device not found")
+			return nil, fmt.Errorf("Device not found")
 		}
 
-		return barrelman.Device{}, fmt.Errorf("couch/GetDevice get doc: %w", err)
+		return nil, fmt.Errorf("couch/GetDevice get doc: %w", err)
 	}
 
 	return convertDevice(dev), nil
 }
 
-func (s *Service) GetRoomDevices(roomID string) ([]barrelman.Device, error) {
-	db := s.client.DB(context.TODO(), _devicesDB)
+// GetRoomDevices returns all of the devices in the given room
+func (s *Service) GetRoomDevices(roomID string) ([]*barrelman.Device, error) {
+	return s.GetRoomDevicesContext(context.Background(), roomID)
+}
+
+// GetRoomDevicesContext returns all of the devices in the given room
+func (s *Service) GetRoomDevicesContext(ctx context.Context, roomID string) ([]*barrelman.Device, error) {
+	db := s.client.DB(ctx, _devicesDB)
 
 	// Query
 	q := query{
@@ -57,13 +69,13 @@ func (s *Service) GetRoomDevices(roomID string) ([]barrelman.Device, error) {
 	}
 
 	// Make the request
-	rows, err := db.Find(context.TODO(), q)
+	rows, err := db.Find(ctx, q)
 	if err != nil {
 		return nil, fmt.Errorf("couch/GetRoomDevices couch request: %w", err)
 	}
 
 	// Convert the devices
-	devs := []barrelman.Device{}
+	devs := []*barrelman.Device{}
 	for rows.Next() {
 		d := device{}
 		err := rows.ScanDoc(&d)
@@ -76,8 +88,16 @@ func (s *Service) GetRoomDevices(roomID string) ([]barrelman.Device, error) {
 	return devs, nil
 }
 
+// GetCentralMonitoringDevice returns the central monitoring device with the
+// given name
 func (s *Service) GetCentralMonitoringDevice(name string) (*barrelman.Device, error) {
-	devs, err := s.GetAllCentralMonitoringDevices()
+	return s.GetCentralMonitoringDeviceContext(context.Background(), name)
+}
+
+// GetCentralMonitoringDeviceContext returns the central monitoring device
+// with the given name
+func (s *Service) GetCentralMonitoringDeviceContext(ctx context.Context, name string) (*barrelman.Device, error) {
+	devs, err := s.GetAllCentralMonitoringDevicesContext(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("get all devices: %w", err)
 	}
@@ -92,11 +112,18 @@ func (s *Service) GetCentralMonitoringDevice(name string) (*barrelman.Device, er
 	return nil, fmt.Errorf("Device not found")
 }
 
+// GetAllCentralMonitoringDevices returns all of the central monitoring devices
 func (s *Service) GetAllCentralMonitoringDevices() ([]*barrelman.Device, error) {
-	db := s.client.DB(context.TODO(), _centralMonitoringDB)
+	return s.GetAllCentralMonitoringDevicesContext(context.Background())
+}
+
+// GetAllCentralMonitoringDevicesContext returns all of the central monitoring
+// devices
+func (s *Service) GetAllCentralMonitoringDevicesContext(ctx context.Context) ([]*barrelman.Device, error) {
+	db := s.client.DB(ctx, _centralMonitoringDB)
 
 	doc := centralDevicesDoc{}
-	err := db.Get(context.TODO(), "default").ScanDoc(&doc)
+	err := db.Get(ctx, "default").ScanDoc(&doc)
 	if err != nil {
 		return nil, fmt.Errorf("retrieving central monitoring doc: %w", err)
 	}
@@ -104,19 +131,38 @@ func (s *Service) GetAllCentralMonitoringDevices() ([]*barrelman.Device, error)
 	// Convert types
 	devs := []*barrelman.Device{}
 	for _, d := range doc.Devices {
-		devs = append(devs, &barrelman.Device{
-			Name:    d.Name,
-			Address: d.Address,
-		})
+		devs = append(devs, convertCentralDevice(d))
 	}
 
 	return devs, nil
 }
 
-func convertDevice(d device) barrelman.Device {
-	return barrelman.Device{
+func convertDevice(d device) *barrelman.Device {
+	return &barrelman.Device{
 		Name:    d.Name,
 		Address: d.Address,
 		Room:    d.Room,
 	}
 }
+
+func convertCentralDevice(d centralDevice) *barrelman.Device {
+	return &barrelman.Device{
+		Name:          d.Name,
+		Address:       d.Address,
+		Room:          d.Room,
+		CheckerConfig: convertCheckerConfig(d.CheckerConfig),
+	}
+}
+
+// convertCheckerConfig converts the checker config of a central monitoring
+// device, which maps each checker name to an object of options for it
+func convertCheckerConfig(c map[string]interface{}) map[string]barrelman.CheckerConfig {
+	conf := make(map[string]barrelman.CheckerConfig, len(c))
+	for checker, v := range c {
+		if opts, ok := v.(map[string]interface{}); ok {
+			conf[checker] = opts
+		}
+	}
+
+	return conf
+}
@@ -1,16 +1,130 @@
 package barrelman
 
+import (
+	"context"
+	"reflect"
+	"sort"
+)
+
 // Device represents a physical device to be monitored
 type Device struct {
 	Name    string
 	Address string
 	Room    string
+
+	// Tags are arbitrary labels used to group devices
+	Tags []string
+
+	// CheckerConfig is a map of checker name to device specific configuration
+	// for that checker
+	CheckerConfig map[string]CheckerConfig
+}
+
+// Config returns the device specific configuration for the given checker.
+// The returned config is empty if the device has no configuration for it
+func (d *Device) Config(checker string) CheckerConfig {
+	if d == nil || d.CheckerConfig == nil {
+		return CheckerConfig{}
+	}
+
+	if c, ok := d.CheckerConfig[checker]; ok && c != nil {
+		return c
+	}
+
+	return CheckerConfig{}
 }
 
-// DeviceStore is the interface to be met by the storage mechanism for device information
+// DeviceStore is the interface to be met by the storage mechanism for device information.
+// Each method has a Context variant which should be used when the caller has
+// a deadline or may need to cancel the request
 type DeviceStore interface {
 	GetCentralMonitoringDevice(name string) (*Device, error)
 	GetAllCentralMonitoringDevices() ([]*Device, error)
 	GetRoomDevices(roomID string) ([]*Device, error)
 	GetDevice(name string) (*Device, error)
+
+	GetCentralMonitoringDeviceContext(ctx context.Context, name string) (*Device, error)
+	GetAllCentralMonitoringDevicesContext(ctx context.Context) ([]*Device, error)
+	GetRoomDevicesContext(ctx context.Context, roomID string) ([]*Device, error)
+	GetDeviceContext(ctx context.Context, name string) (*Device, error)
+}
+
+// DeviceChangeType is the type of change made to a device in a DeviceStore
+type DeviceChangeType int
+
+const (
+	// DeviceAdded means that the device was added to the store
+	DeviceAdded DeviceChangeType = iota
+
+	// DeviceUpdated means that the device was modified in the store
+	DeviceUpdated
+
+	// DeviceRemoved means that the device was removed from the store. Only
+	// the Name of the device is guaranteed to be set
+	DeviceRemoved
+)
+
+// String returns the name of the change type
+func (t DeviceChangeType) String() string {
+	switch t {
+	case DeviceAdded:
+		return "added"
+	case DeviceUpdated:
+		return "updated"
+	case DeviceRemoved:
+		return "removed"
+	}
+
+	return "unknown"
+}
+
+// DeviceChange is a notification that a device in a DeviceStore has changed
+type DeviceChange struct {
+	Type   DeviceChangeType
+	Device *Device
+}
+
+// DeviceWatcher is the interface to be met by a DeviceStore which can notify
+// callers of changes to its devices. The returned channels are closed when the
+// given context is canceled
+type DeviceWatcher interface {
+	WatchRoomDevices(ctx context.Context, roomID string) (<-chan DeviceChange, error)
+	WatchCentralMonitoringDevices(ctx context.Context) (<-chan DeviceChange, error)
+}
+
+// DiffDevices returns the changes needed to get from one list of devices to
+// another, matching devices by name
+func DiffDevices(from, to []*Device) []DeviceChange {
+	oldDevs := make(map[string]*Device, len(from))
+	for _, d := range from {
+		oldDevs[d.Name] = d
+	}
+
+	newDevs := make(map[string]*Device, len(to))
+	changes := []DeviceChange{}
+	for _, d := range to {
+		newDevs[d.Name] = d
+
+		o, ok := oldDevs[d.Name]
+		switch {
+		case !ok:
+			changes = append(changes, DeviceChange{Type: DeviceAdded, Device: d})
+		case !reflect.DeepEqual(o, d):
+			changes = append(changes, DeviceChange{Type: DeviceUpdated, Device: d})
+		}
+	}
+
+	removed := []string{}
+	for name := range oldDevs {
+		if _, ok := newDevs[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+
+	sort.Strings(removed)
+	for _, name := range removed {
+		changes = append(changes, DeviceChange{Type: DeviceRemoved, Device: &Device{Name: name}})
+	}
+
+	return changes
 }
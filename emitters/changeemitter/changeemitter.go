@@ -0,0 +1,120 @@
+package changeemitter
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/byuoitav/barrelman"
+)
+
+var _ barrelman.EventEmitter = (*Emitter)(nil)
+
+// _queueSize is the number of events that can be waiting to be sent to the
+// wrapped emitter before Send blocks
+const _queueSize = 1000
+
+// Emitter wraps another EventEmitter and only passes events through to it
+// when the value of an event has changed since it was last emitted for the
+// same device and key. Events are passed through in the order they were sent
+//
+// Metrics are not compared, so an event whose metrics changed but whose value
+// didn't is still dropped. Use a heartbeat to sample metrics regularly. This
+// is deliberately different from the monitor's update stream, which carries
+// every result with its metrics, because the emitter exists to keep the
+// event hub from being flooded with unchanged state
+type Emitter struct {
+	// Options
+	heartbeat int
+
+	emitter barrelman.EventEmitter
+	queue   chan barrelman.Event
+
+	lastMu sync.Mutex
+	last   map[eventKey]emitted
+}
+
+// eventKey identifies a stream of events
+type eventKey struct {
+	device string
+	key    string
+}
+
+// emitted is the last value emitted for an eventKey and when it was emitted
+type emitted struct {
+	value string
+	time  time.Time
+}
+
+// Option is a function which modifies an Emitter. This allows the user to set
+// options that have been exposed
+type Option func(*Emitter)
+
+// WithHeartbeat allows the user to set an interval (in seconds) after which an
+// unchanged event will be emitted again so that downstream systems still see
+// the current state after they restart. The default of 0 disables heartbeats
+func WithHeartbeat(h int) Option {
+	return func(e *Emitter) {
+		e.heartbeat = h
+	}
+}
+
+// NewEmitter returns a new Emitter which sends changed events to the given
+// EventEmitter with the given options set
+func NewEmitter(emitter barrelman.EventEmitter, opts ...Option) (*Emitter, error) {
+	if emitter == nil {
+		return nil, fmt.Errorf("An event emitter is required")
+	}
+
+	e := Emitter{
+		emitter: emitter,
+		queue:   make(chan barrelman.Event, _queueSize),
+		last:    make(map[eventKey]emitted),
+	}
+
+	// Apply options
+	for _, opt := range opts {
+		opt(&e)
+	}
+
+	go e.send()
+
+	return &e, nil
+}
+
+// Send passes the event through to the wrapped emitter if it is the first
+// event for its device and key, if its value has changed, or if the
+// heartbeat interval has passed since the event was last emitted. Events are
+// queued while the lock is held, so that two quick changes to the same key
+// can't reach the wrapped emitter out of order
+func (e *Emitter) Send(event barrelman.Event) {
+	k := eventKey{
+		device: event.Device.Name,
+		key:    event.Key,
+	}
+	now := time.Now()
+
+	e.lastMu.Lock()
+	defer e.lastMu.Unlock()
+
+	last, ok := e.last[k]
+	changed := !ok || last.value != event.Value
+	expired := e.heartbeat > 0 && now.Sub(last.time) >= time.Duration(e.heartbeat)*time.Second
+	if !changed && !expired {
+		return
+	}
+
+	e.last[k] = emitted{
+		value: event.Value,
+		time:  now,
+	}
+
+	e.queue <- event
+}
+
+// send passes queued events through to the wrapped emitter one at a time
+func (e *Emitter) send() {
+	for event := range e.queue {
+		e.emitter.Send(event)
+	}
+}
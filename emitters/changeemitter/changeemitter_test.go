@@ -0,0 +1,141 @@
+package changeemitter
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/byuoitav/barrelman"
+)
+
+// recorder is an EventEmitter which records the events sent to it
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) Send(e barrelman.Event) {
+	// Slow enough that queued events pile up behind it
+	time.Sleep(time.Millisecond)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, fmt.Sprintf("%s %s=%s", e.Device.Name, e.Key, e.Value))
+}
+
+// wait returns the recorded events once n have been sent
+func (r *recorder) wait(t *testing.T, n int) []string {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		r.mu.Lock()
+		events := append([]string(nil), r.events...)
+		r.mu.Unlock()
+
+		if len(events) >= n {
+			return events
+		}
+	}
+
+	t.Fatalf("timed out waiting for %d events", n)
+	return nil
+}
+
+func newTestEmitter(t *testing.T, opts ...Option) (*Emitter, *recorder) {
+	t.Helper()
+
+	r := &recorder{}
+	e, err := NewEmitter(r, opts...)
+	if err != nil {
+		t.Fatalf("failed to create emitter: %s", err)
+	}
+
+	return e, r
+}
+
+func TestSendOnlyChanges(t *testing.T) {
+	e, r := newTestEmitter(t)
+	d := &barrelman.Device{Name: "ITB-1101-CP1"}
+
+	for _, v := range []string{"Ok", "Ok", "Failed", "Failed", "Ok"} {
+		e.Send(barrelman.Event{Device: d, Key: "ping", Value: v})
+	}
+	e.Send(barrelman.Event{Device: d, Key: "health", Value: "Ok"})
+
+	want := fmt.Sprint([]string{
+		"ITB-1101-CP1 ping=Ok",
+		"ITB-1101-CP1 ping=Failed",
+		"ITB-1101-CP1 ping=Ok",
+		"ITB-1101-CP1 health=Ok",
+	})
+
+	// Give any extra events a chance to arrive
+	r.wait(t, 4)
+	time.Sleep(20 * time.Millisecond)
+	got := r.wait(t, 4)
+
+	if fmt.Sprint(got) != want {
+		t.Errorf("got events %v, want %v", got, want)
+	}
+}
+
+func TestSendInOrder(t *testing.T) {
+	e, r := newTestEmitter(t)
+
+	// Each device flaps quickly from its own goroutine. The last value sent
+	// for each device must be the last one emitted for it
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(d *barrelman.Device) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				value := "Ok"
+				if j%2 == 0 {
+					value = "Failed"
+				}
+
+				e.Send(barrelman.Event{Device: d, Key: "ping", Value: value})
+			}
+		}(&barrelman.Device{Name: fmt.Sprintf("ITB-1101-D%d", i)})
+	}
+	wg.Wait()
+
+	events := r.wait(t, 200)
+	last := make(map[string]string)
+	seen := make(map[string]int)
+	for _, ev := range events {
+		var name, kv string
+		fmt.Sscan(ev, &name, &kv)
+
+		if prev, ok := last[name]; ok && prev == kv {
+			t.Fatalf("%s emitted %s twice in a row", name, kv)
+		}
+
+		last[name] = kv
+		seen[name]++
+	}
+
+	for name, kv := range last {
+		if kv != "ping=Ok" || seen[name] != 20 {
+			t.Errorf("%s ended on %s after %d events, want ping=Ok after 20", name, kv, seen[name])
+		}
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	e, r := newTestEmitter(t, WithHeartbeat(1))
+	d := &barrelman.Device{Name: "ITB-1101-CP1"}
+
+	e.Send(barrelman.Event{Device: d, Key: "ping", Value: "Ok"})
+	e.Send(barrelman.Event{Device: d, Key: "ping", Value: "Ok"})
+	r.wait(t, 1)
+
+	time.Sleep(1100 * time.Millisecond)
+	e.Send(barrelman.Event{Device: d, Key: "ping", Value: "Ok"})
+
+	if got := r.wait(t, 2); len(got) != 2 {
+		t.Errorf("got events %v, want 2", got)
+	}
+}
@@ -8,4 +8,7 @@ type Event struct {
 	Device *Device
 	Key    string
 	Value  string
+
+	// Metrics are the metrics of the check that generated the event
+	Metrics map[string]Metric
 }
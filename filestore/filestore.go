@@ -0,0 +1,339 @@
+package filestore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/byuoitav/barrelman"
+	"gopkg.in/yaml.v2"
+)
+
+var _ barrelman.DeviceStore = (*Store)(nil)
+var _ barrelman.DeviceWatcher = (*Store)(nil)
+
+// Store is a DeviceStore backed by a local YAML or JSON file. Files ending in
+// .json are parsed as JSON and all others as YAML. The file is reloaded
+// whenever it changes
+type Store struct {
+	// Options
+	reloadInterval int
+
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	devices []*barrelman.Device
+	central []*barrelman.Device
+}
+
+// file is the format of a device file
+type file struct {
+	Devices []device `json:"devices" yaml:"devices"`
+}
+
+type device struct {
+	Name    string   `json:"name" yaml:"name"`
+	Address string   `json:"address" yaml:"address"`
+	Room    string   `json:"room" yaml:"room"`
+	Tags    []string `json:"tags" yaml:"tags"`
+
+	// CentralMonitoring marks the device as one to be monitored centrally
+	CentralMonitoring bool `json:"centralMonitoring" yaml:"centralMonitoring"`
+
+	CheckerConfig map[string]map[string]interface{} `json:"checkerConfig" yaml:"checkerConfig"`
+}
+
+// Option is a function which modifies a Store. This allows the user to set
+// options that have been exposed
+type Option func(*Store)
+
+// WithReloadInterval allows the user to set how often (in seconds) watchers
+// check the file for changes. The default is 5 seconds
+func WithReloadInterval(i int) Option {
+	return func(s *Store) {
+		s.reloadInterval = i
+	}
+}
+
+// New returns a new Store backed by the file at the given path with the given
+// options set. The file must exist and be valid
+func New(path string, opts ...Option) (*Store, error) {
+	s := Store{
+		reloadInterval: 5,
+		path:           path,
+	}
+
+	// Apply options
+	for _, opt := range opts {
+		opt(&s)
+	}
+
+	if s.reloadInterval < 1 {
+		s.reloadInterval = 1
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("Stat device file: %w", err)
+	}
+
+	if err := s.load(info); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// GetDevice returns the device with the given name
+func (s *Store) GetDevice(name string) (*barrelman.Device, error) {
+	return s.GetDeviceContext(context.Background(), name)
+}
+
+// GetDeviceContext returns the device with the given name
+func (s *Store) GetDeviceContext(ctx context.Context, name string) (*barrelman.Device, error) {
+	devs, _ := s.snapshot()
+	for _, d := range devs {
+		if d.Name == name {
+			return d, nil
+		}
+	}
+
+	return nil, fmt.Errorf("Device not found")
+}
+
+// GetRoomDevices returns all of the devices in the given room
+func (s *Store) GetRoomDevices(roomID string) ([]*barrelman.Device, error) {
+	return s.GetRoomDevicesContext(context.Background(), roomID)
+}
+
+// GetRoomDevicesContext returns all of the devices in the given room
+func (s *Store) GetRoomDevicesContext(ctx context.Context, roomID string) ([]*barrelman.Device, error) {
+	devs, _ := s.snapshot()
+	return filter(devs, inRoom(roomID)), nil
+}
+
+// GetCentralMonitoringDevice returns the central monitoring device with the
+// given name
+func (s *Store) GetCentralMonitoringDevice(name string) (*barrelman.Device, error) {
+	return s.GetCentralMonitoringDeviceContext(context.Background(), name)
+}
+
+// GetCentralMonitoringDeviceContext returns the central monitoring device
+// with the given name
+func (s *Store) GetCentralMonitoringDeviceContext(ctx context.Context, name string) (*barrelman.Device, error) {
+	devs, err := s.GetAllCentralMonitoringDevicesContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get all devices: %w", err)
+	}
+
+	for _, d := range devs {
+		if d.Name == name {
+			return d, nil
+		}
+	}
+
+	return nil, fmt.Errorf("Device not found")
+}
+
+// GetAllCentralMonitoringDevices returns all of the devices marked for
+// central monitoring
+func (s *Store) GetAllCentralMonitoringDevices() ([]*barrelman.Device, error) {
+	return s.GetAllCentralMonitoringDevicesContext(context.Background())
+}
+
+// GetAllCentralMonitoringDevicesContext returns all of the devices marked
+// for central monitoring
+func (s *Store) GetAllCentralMonitoringDevicesContext(ctx context.Context) ([]*barrelman.Device, error) {
+	_, central := s.snapshot()
+	return central, nil
+}
+
+// WatchRoomDevices sends a DeviceChange on the returned channel each time a
+// device in the given room is added, updated, or removed from the file
+func (s *Store) WatchRoomDevices(ctx context.Context, roomID string) (<-chan barrelman.DeviceChange, error) {
+	return s.watch(ctx, func() []*barrelman.Device {
+		devs, _ := s.snapshot()
+		return filter(devs, inRoom(roomID))
+	}), nil
+}
+
+// WatchCentralMonitoringDevices sends a DeviceChange on the returned channel
+// each time a central monitoring device is added, updated, or removed from
+// the file
+func (s *Store) WatchCentralMonitoringDevices(ctx context.Context) (<-chan barrelman.DeviceChange, error) {
+	return s.watch(ctx, func() []*barrelman.Device {
+		_, central := s.snapshot()
+		return central
+	}), nil
+}
+
+// watch polls the given list of devices on the reload interval and sends the
+// changes between each poll on the returned channel until the context is
+// canceled
+func (s *Store) watch(ctx context.Context, list func() []*barrelman.Device) <-chan barrelman.DeviceChange {
+	changes := make(chan barrelman.DeviceChange)
+
+	go func() {
+		defer close(changes)
+
+		ticker := time.NewTicker(time.Duration(s.reloadInterval) * time.Second)
+		defer ticker.Stop()
+
+		devs := list()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			current := list()
+			for _, c := range barrelman.DiffDevices(devs, current) {
+				select {
+				case changes <- c:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			devs = current
+		}
+	}()
+
+	return changes
+}
+
+// snapshot reloads the file if it has changed and returns the current list
+// of all devices and of the central monitoring devices
+func (s *Store) snapshot() ([]*barrelman.Device, []*barrelman.Device) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, err := os.Stat(s.path)
+	switch {
+	case err != nil:
+		log.Printf("Failed to stat device file, using last loaded devices: %s", err)
+	case !info.ModTime().Equal(s.modTime) || info.Size() != s.size:
+		if err := s.load(info); err != nil {
+			log.Printf("Failed to reload device file, using last loaded devices: %s", err)
+		}
+	}
+
+	return s.devices, s.central
+}
+
+// load parses the file and replaces the loaded devices. The caller must hold
+// the lock, unless the store hasn't been returned yet
+func (s *Store) load(info os.FileInfo) error {
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("Reading device file: %w", err)
+	}
+
+	f := file{}
+	if strings.EqualFold(filepath.Ext(s.path), ".json") {
+		err = json.Unmarshal(data, &f)
+	} else {
+		err = yaml.UnmarshalStrict(data, &f)
+	}
+	if err != nil {
+		return fmt.Errorf("Parsing device file: %w", err)
+	}
+
+	devs := []*barrelman.Device{}
+	central := []*barrelman.Device{}
+	for _, d := range f.Devices {
+		if d.Name == "" {
+			return fmt.Errorf("Parsing device file: device with address %q has no name", d.Address)
+		}
+
+		dev := convertDevice(d)
+		devs = append(devs, dev)
+		if d.CentralMonitoring {
+			central = append(central, dev)
+		}
+	}
+
+	s.devices = devs
+	s.central = central
+	s.modTime = info.ModTime()
+	s.size = info.Size()
+
+	return nil
+}
+
+func convertDevice(d device) *barrelman.Device {
+	dev := &barrelman.Device{
+		Name:          d.Name,
+		Address:       d.Address,
+		Room:          d.Room,
+		Tags:          d.Tags,
+		CheckerConfig: make(map[string]barrelman.CheckerConfig, len(d.CheckerConfig)),
+	}
+
+	for checker, conf := range d.CheckerConfig {
+		converted := make(barrelman.CheckerConfig, len(conf))
+		for key, value := range conf {
+			converted[key] = convertValue(value)
+		}
+
+		dev.CheckerConfig[checker] = converted
+	}
+
+	return dev
+}
+
+// convertValue converts the map[interface{}]interface{} that YAML decodes
+// nested objects into to the map[string]interface{} that JSON produces, so
+// that checkers see the same config regardless of the file's format
+func convertValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = convertValue(value)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[key] = convertValue(value)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, value := range v {
+			s[i] = convertValue(value)
+		}
+		return s
+	}
+
+	return v
+}
+
+// filter returns the devices for which keep returns true
+func filter(devs []*barrelman.Device, keep func(*barrelman.Device) bool) []*barrelman.Device {
+	filtered := []*barrelman.Device{}
+	for _, d := range devs {
+		if keep(d) {
+			filtered = append(filtered, d)
+		}
+	}
+
+	return filtered
+}
+
+func inRoom(roomID string) func(*barrelman.Device) bool {
+	return func(d *barrelman.Device) bool {
+		return d.Room == roomID
+	}
+}
@@ -0,0 +1,137 @@
+package filestore
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const _nestedYAML = `
+devices:
+  - name: ITB-1101-SW1
+    address: itb-1101-sw1.byu.edu
+    room: ITB-1101
+    checkerConfig:
+      http:
+        path: /status
+        headers:
+          Accept: application/json
+          X-Retries: 3
+      snmp:
+        community: monitor
+        assertions:
+          - name: ifOperStatus
+            oid: 1.3.6.1.2.1.2.2.1.8.1
+            op: eq
+            value: 1
+          - name: temperature
+            oid: 1.3.6.1.4.1.9.9.13.1.3.1.3.1
+            op: lt
+            value: 60
+`
+
+const _nestedJSON = `{
+  "devices": [{
+    "name": "ITB-1101-SW1",
+    "address": "itb-1101-sw1.byu.edu",
+    "room": "ITB-1101",
+    "checkerConfig": {
+      "http": {
+        "path": "/status",
+        "headers": {"Accept": "application/json", "X-Retries": 3}
+      },
+      "snmp": {
+        "community": "monitor",
+        "assertions": [
+          {"name": "ifOperStatus", "oid": "1.3.6.1.2.1.2.2.1.8.1", "op": "eq", "value": 1},
+          {"name": "temperature", "oid": "1.3.6.1.4.1.9.9.13.1.3.1.3.1", "op": "lt", "value": 60}
+        ]
+      }
+    }
+  }]
+}`
+
+func writeFile(t *testing.T, name, contents string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatalf("failed to write device file: %s", err)
+	}
+
+	return path
+}
+
+func TestNestedCheckerConfig(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		contents string
+	}{
+		{"devices.yaml", _nestedYAML},
+		{"devices.json", _nestedJSON},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(writeFile(t, tt.name, tt.contents))
+			if err != nil {
+				t.Fatalf("failed to load device file: %s", err)
+			}
+
+			d, err := s.GetDevice("ITB-1101-SW1")
+			if err != nil {
+				t.Fatalf("failed to get device: %s", err)
+			}
+
+			headers, ok := d.Config("http")["headers"].(map[string]interface{})
+			if !ok {
+				t.Fatalf("headers decoded as %T, want map[string]interface{}", d.Config("http")["headers"])
+			}
+
+			if headers["Accept"] != "application/json" {
+				t.Errorf("got Accept header %v, want application/json", headers["Accept"])
+			}
+
+			assertions, ok := d.Config("snmp")["assertions"].([]interface{})
+			if !ok || len(assertions) != 2 {
+				t.Fatalf("assertions decoded as %#v, want a list of 2", d.Config("snmp")["assertions"])
+			}
+
+			for i, a := range assertions {
+				m, ok := a.(map[string]interface{})
+				if !ok {
+					t.Fatalf("assertion %d decoded as %T, want map[string]interface{}", i, a)
+				}
+
+				if oid, _ := m["oid"].(string); oid == "" {
+					t.Errorf("assertion %d has no oid: %v", i, m)
+				}
+			}
+
+			if community, _ := d.Config("snmp").String("community"); community != "monitor" {
+				t.Errorf("got community %q, want monitor", community)
+			}
+		})
+	}
+}
+
+func TestConvertValue(t *testing.T) {
+	in := map[interface{}]interface{}{
+		"list": []interface{}{
+			map[interface{}]interface{}{"a": 1},
+			"b",
+		},
+		1: map[interface{}]interface{}{"nested": true},
+	}
+
+	want := map[string]interface{}{
+		"list": []interface{}{
+			map[string]interface{}{"a": 1},
+			"b",
+		},
+		"1": map[string]interface{}{"nested": true},
+	}
+
+	if got := convertValue(in); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
@@ -11,9 +11,12 @@ require (
 	github.com/go-kivik/kivik/v3 v3.2.0
 	github.com/go-ping/ping v0.0.0-20201001214134-671c40f29adc
 	github.com/gorilla/websocket v1.4.2 // indirect
+	github.com/gosnmp/gosnmp v1.32.0
 	github.com/labstack/echo v3.3.10+incompatible // indirect
 	github.com/labstack/gommon v0.3.0 // indirect
 	github.com/spf13/pflag v1.0.5
 	go.uber.org/zap v1.16.0 // indirect
+	golang.org/x/net v0.0.0-20201110031124-69a78807bb2b
 	golang.org/x/sync v0.0.0-20201207232520-09787c993a3a
+	gopkg.in/yaml.v2 v2.4.0
 )
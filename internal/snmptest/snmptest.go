@@ -0,0 +1,181 @@
+// Package snmptest provides a stand-in SNMP agent for testing the checkers
+// that query devices over SNMP
+package snmptest
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// usmStatsUnknownEngineIDs is reported to v3 clients to tell them the agent's
+// engine ID during discovery
+const usmStatsUnknownEngineIDs = ".1.3.6.1.6.3.15.1.1.4.0"
+
+// Agent is a stand-in SNMP agent listening on a loopback UDP port. It answers
+// GETs from a fixed set of values, replying NoSuchInstance for unknown OIDs.
+// It accepts v2c requests with the public community unless WithV3 is used
+type Agent struct {
+	conn      *net.UDPConn
+	values    map[string]gosnmp.SnmpPDU
+	community string
+
+	// v3 is the user the agent accepts, or nil for a v2c agent
+	v3       *gosnmp.UsmSecurityParameters
+	v3Flags  gosnmp.SnmpV3MsgFlags
+	engineID string
+}
+
+// NewAgent returns an agent which answers with the given values, keyed by OID
+// without a leading dot. The agent is closed when the test finishes
+func NewAgent(tb testing.TB, values map[string]gosnmp.SnmpPDU) *Agent {
+	tb.Helper()
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		tb.Fatalf("failed to listen: %s", err)
+	}
+	tb.Cleanup(func() { conn.Close() })
+
+	return &Agent{
+		conn:      conn,
+		values:    values,
+		community: "public",
+		engineID:  "\x80\x00\x1f\x88\x80barrelman",
+	}
+}
+
+// WithV3 makes the agent only accept v3 requests from the given user
+func (a *Agent) WithV3(flags gosnmp.SnmpV3MsgFlags, user *gosnmp.UsmSecurityParameters) *Agent {
+	a.v3 = user
+	a.v3Flags = flags
+	return a
+}
+
+// Port returns the port the agent is listening on
+func (a *Agent) Port() int {
+	return a.conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+// Close stops the agent. Requests sent to its port are refused afterward
+func (a *Agent) Close() {
+	a.conn.Close()
+}
+
+// Serve answers requests until the agent is closed
+func (a *Agent) Serve() {
+	buf := make([]byte, 65535)
+	for {
+		n, from, err := a.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+
+		resp := a.respond(append([]byte(nil), buf[:n]...))
+		if resp == nil {
+			continue
+		}
+
+		b, err := resp.MarshalMsg()
+		if err != nil {
+			continue
+		}
+
+		a.conn.WriteToUDP(b, from)
+	}
+}
+
+// respond returns the reply to the given request, or nil if the request
+// should be ignored
+func (a *Agent) respond(req []byte) *gosnmp.SnmpPacket {
+	decoder := &gosnmp.GoSNMP{
+		Version:   gosnmp.Version2c,
+		Community: a.community,
+	}
+
+	if a.v3 != nil {
+		decoder = &gosnmp.GoSNMP{
+			Version:       gosnmp.Version3,
+			SecurityModel: gosnmp.UserSecurityModel,
+			MsgFlags:      a.v3Flags,
+			SecurityParameters: &gosnmp.UsmSecurityParameters{
+				UserName:                 a.v3.UserName,
+				AuthenticationProtocol:   a.v3.AuthenticationProtocol,
+				AuthenticationPassphrase: a.v3.AuthenticationPassphrase,
+				PrivacyProtocol:          a.v3.PrivacyProtocol,
+				PrivacyPassphrase:        a.v3.PrivacyPassphrase,
+			},
+		}
+	}
+
+	packet, err := decoder.SnmpDecodePacket(req)
+	if err != nil || packet.PDUType != gosnmp.GetRequest {
+		return nil
+	}
+
+	if packet.Version != decoder.Version {
+		return nil
+	}
+
+	resp := &gosnmp.SnmpPacket{
+		Version:   packet.Version,
+		Community: packet.Community,
+		PDUType:   gosnmp.GetResponse,
+		RequestID: packet.RequestID,
+	}
+
+	switch packet.Version {
+	case gosnmp.Version2c:
+		if packet.Community != a.community {
+			return nil
+		}
+	case gosnmp.Version3:
+		sp := packet.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+		if sp.UserName != "" && sp.UserName != a.v3.UserName {
+			return nil
+		}
+
+		resp.MsgID = packet.MsgID
+		resp.SecurityModel = gosnmp.UserSecurityModel
+		resp.ContextEngineID = a.engineID
+
+		// Tell the client our engine ID so it can localize its keys
+		if sp.AuthoritativeEngineID == "" {
+			resp.PDUType = gosnmp.Report
+			resp.MsgFlags = gosnmp.NoAuthNoPriv
+			resp.SecurityParameters = &gosnmp.UsmSecurityParameters{
+				AuthoritativeEngineID:    a.engineID,
+				AuthoritativeEngineBoots: 1,
+				AuthoritativeEngineTime:  1,
+			}
+			resp.Variables = []gosnmp.SnmpPDU{
+				{Name: usmStatsUnknownEngineIDs, Type: gosnmp.Counter32, Value: uint32(1)},
+			}
+
+			return resp
+		}
+
+		if packet.MsgFlags&gosnmp.AuthPriv != a.v3Flags {
+			return nil
+		}
+
+		// The decoded parameters hold the keys localized to our engine ID
+		resp.MsgFlags = packet.MsgFlags &^ gosnmp.Reportable
+		resp.SecurityParameters = sp
+		resp.ContextName = packet.ContextName
+	}
+
+	for _, v := range packet.Variables {
+		pdu, ok := a.values[strings.TrimPrefix(v.Name, ".")]
+		if !ok {
+			pdu = gosnmp.SnmpPDU{Type: gosnmp.NoSuchInstance}
+		}
+
+		pdu.Name = v.Name
+		resp.Variables = append(resp.Variables, pdu)
+	}
+
+	return resp
+}
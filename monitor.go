@@ -1,21 +1,65 @@
 package barrelman
 
-import "time"
+import "log"
 
 // DeviceMonitor runs registered checkers on registered devices and reports on
 // the outcomes of the checks according to the individual monitor's
 // implementation details.
 type DeviceMonitor interface {
-	RegisterChecker(name string, interval time.Duration, c *Checker) error
+	// RegisterChecker registers a checker to be run on all registered devices
+	// on the given interval (measured in seconds)
+	RegisterChecker(name string, interval int, c Checker) error
 	RegisterDevice(*Device) error
 
+	// UnregisterDevice stops all checks on the given device name and forgets
+	// its status
+	UnregisterDevice(name string) error
+
+	// UpdateDevice replaces the registered device with the same name as the
+	// given device, keeping the results of its previous checks
+	UpdateDevice(*Device) error
+
 	// ForceCheck forces the DeviceMonitor to immediately run all checks for the
 	// given device name.
 	ForceCheck(name string) error
 	Status(name string) (DeviceStatus, error)
+
+	// AllDevices returns the current status of every registered device
+	AllDevices() []DeviceStatus
 }
 
+// RoomMonitor rolls up the status of the devices being monitored into a status
+// for each of the rooms that those devices belong to
 type RoomMonitor interface {
+	// RoomStatus returns the current status of the given room (by ID)
+	RoomStatus(roomID string) (RoomStatus, error)
+
+	// AllRooms returns the current status of every room with at least one
+	// monitored device
+	AllRooms() []RoomStatus
+}
+
+// CheckSubscriber is met by monitors which can stream check results to
+// subscribers as they come in
+type CheckSubscriber interface {
+	// Subscribe returns a channel on which an update will be sent each time a
+	// check result is recorded by the monitor
+	Subscribe() <-chan CheckUpdate
+
+	// Unsubscribe stops updates from being sent on the given channel and
+	// closes it
+	Unsubscribe(<-chan CheckUpdate)
+}
+
+// CheckUpdate is sent to subscribers each time a check result is recorded
+type CheckUpdate struct {
+	Device  *Device
+	Checker string
+
+	// Healthy is the health of the device after the result was recorded
+	Healthy bool
+
+	Result CheckResult
 }
 
 // DeviceStatus is a representation of a device's monitoring status
@@ -31,3 +75,65 @@ type DeviceStatus struct {
 	// DeviceMonitor to their detailed status information
 	CheckStatus map[string]CheckResult
 }
+
+// RoomState is the overall state of a room
+type RoomState string
+
+const (
+	// RoomHealthy means that every device in the room is healthy
+	RoomHealthy RoomState = "healthy"
+
+	// RoomDegraded means that some, but not all, of the devices in the room
+	// are unhealthy
+	RoomDegraded RoomState = "degraded"
+
+	// RoomDown means that none of the devices in the room are healthy
+	RoomDown RoomState = "down"
+)
+
+// RoomStatus is a representation of a room's monitoring status
+type RoomStatus struct {
+	Room  string
+	State RoomState
+
+	// Counts of the devices in the room
+	DeviceCount    int
+	HealthyCount   int
+	UnhealthyCount int
+
+	// FailingCheckers is a map of the name of each checker that is currently
+	// failing on at least one device in the room to the names of the devices
+	// it is failing on
+	FailingCheckers map[string][]string
+
+	// Devices is the status of each of the devices in the room
+	Devices []DeviceStatus
+}
+
+// SyncDevices applies the device changes received on the given channel to the
+// given DeviceMonitor until the channel is closed. Added devices which are
+// already registered are updated, and updated devices which aren't registered
+// yet are registered, so that the monitor converges on the store's state
+func SyncDevices(m DeviceMonitor, changes <-chan DeviceChange) {
+	for c := range changes {
+		var err error
+
+		switch c.Type {
+		case DeviceAdded, DeviceUpdated:
+			if _, serr := m.Status(c.Device.Name); serr == nil {
+				err = m.UpdateDevice(c.Device)
+			} else {
+				err = m.RegisterDevice(c.Device)
+			}
+		case DeviceRemoved:
+			err = m.UnregisterDevice(c.Device.Name)
+		}
+
+		if err != nil {
+			log.Printf("Failed to sync %s device %s: %s", c.Type, c.Device.Name, err)
+			continue
+		}
+
+		log.Printf("Synced %s device %s", c.Type, c.Device.Name)
+	}
+}
@@ -10,17 +10,37 @@ import (
 	"github.com/byuoitav/barrelman"
 )
 
+var _ barrelman.DeviceMonitor = (*Monitor)(nil)
+var _ barrelman.CheckSubscriber = (*Monitor)(nil)
+
+// _schedulerTick is how often the schedule of each checker is evaluated
+const _schedulerTick = time.Second
+
+// _eventBuffer is the number of events that can be waiting on the event
+// emitter before recording check results blocks
+const _eventBuffer = 1000
+
+// _subscriberBuffer is the number of updates that can be waiting on a
+// subscriber before further updates to it are dropped
+const _subscriberBuffer = 100
+
 // Monitor contains all of the data used by the IntervalMonitor
 type Monitor struct {
 	// Options
 	jitter       int
 	eventEmitter barrelman.EventEmitter
+	healthPolicy HealthPolicy
 
+	checkerMu      sync.RWMutex
 	checkers       map[string]barrelman.Checker
 	checkStateChan chan deviceCheckMsg
+	events         chan barrelman.Event
 
 	deviceMu sync.RWMutex
 	devices  map[string]barrelman.DeviceStatus
+
+	subMu       sync.Mutex
+	subscribers map[<-chan barrelman.CheckUpdate]chan barrelman.CheckUpdate
 }
 
 type wrappedChecker struct {
@@ -29,10 +49,20 @@ type wrappedChecker struct {
 	stateChan chan deviceCheckMsg
 }
 
+// schedule is when a checker should next run on a device
+type schedule struct {
+	next     time.Time
+	interval int
+}
+
 type deviceCheckMsg struct {
 	deviceID string
 	checker  string
 	result   *barrelman.CheckResult
+
+	// flushed, if set, marks this message as a flush rather than a result.
+	// It is closed once every message sent before it has been processed
+	flushed chan struct{}
 }
 
 // NewMonitor returns a new IntervalMonitor with the given options set
@@ -40,9 +70,11 @@ func NewMonitor(opts ...Option) (*Monitor, error) {
 	m := Monitor{
 		jitter:         30,
 		eventEmitter:   nil,
+		healthPolicy:   AllPassing(),
 		devices:        make(map[string]barrelman.DeviceStatus),
 		checkers:       make(map[string]barrelman.Checker),
 		checkStateChan: make(chan deviceCheckMsg, 100),
+		subscribers:    make(map[<-chan barrelman.CheckUpdate]chan barrelman.CheckUpdate),
 	}
 
 	// Apply options
@@ -52,6 +84,11 @@ func NewMonitor(opts ...Option) (*Monitor, error) {
 
 	rand.Seed(time.Now().UTC().UnixNano())
 
+	if m.eventEmitter != nil {
+		m.events = make(chan barrelman.Event, _eventBuffer)
+		go m.sendEvents()
+	}
+
 	go m.listenForChecks()
 
 	return &m, nil
@@ -63,21 +100,106 @@ func (m *Monitor) listenForChecks() {
 	for {
 		msg := <-m.checkStateChan
 
-		// Write the new check to the device stateChan
+		if msg.flushed != nil {
+			close(msg.flushed)
+			continue
+		}
+
+		checkers := m.checkerNames()
+
+		// Write the new check to the device state and recompute its health
 		m.deviceMu.Lock()
-		m.devices[msg.deviceID].CheckStatus[msg.checker] = *msg.result
+		status, ok := m.devices[msg.deviceID]
+		if !ok {
+			// The device was unregistered while the check was running
+			m.deviceMu.Unlock()
+			continue
+		}
+
+		status.CheckStatus[msg.checker] = *msg.result
+		status.Healthy = m.healthPolicy(enabledCheckers(status.Device, checkers), status.CheckStatus)
+		m.devices[msg.deviceID] = status
 		m.deviceMu.Unlock()
 
-		// If there is an event emitter then send the event
+		m.publish(barrelman.CheckUpdate{
+			Device:  status.Device,
+			Checker: msg.checker,
+			Healthy: status.Healthy,
+			Result:  *msg.result,
+		})
+
+		// If there is an event emitter then send the events
 		if m.eventEmitter != nil {
-			go m.eventEmitter.Send(msg.result.Event)
+			// Skipped checks and some composite checks have no main event
+			if msg.result.Event.Key != "" {
+				event := msg.result.Event
+				if event.Metrics == nil {
+					event.Metrics = msg.result.Metrics
+				}
+
+				m.events <- event
+			}
+			for _, e := range msg.result.Events {
+				m.events <- e
+			}
+		}
+	}
+}
+
+// sendEvents sends events to the event emitter in the order their checks
+// finished, so that an older value never reaches it after a newer one
+func (m *Monitor) sendEvents() {
+	for e := range m.events {
+		m.eventEmitter.Send(e)
+	}
+}
+
+// Subscribe returns a channel on which an update will be sent each time a
+// check result is recorded. Updates are dropped for subscribers that fall
+// too far behind
+func (m *Monitor) Subscribe() <-chan barrelman.CheckUpdate {
+	ch := make(chan barrelman.CheckUpdate, _subscriberBuffer)
+
+	m.subMu.Lock()
+	m.subscribers[ch] = ch
+	m.subMu.Unlock()
+
+	return ch
+}
+
+// Unsubscribe stops updates from being sent on the given channel and closes it
+func (m *Monitor) Unsubscribe(sub <-chan barrelman.CheckUpdate) {
+	m.subMu.Lock()
+	defer m.subMu.Unlock()
+
+	if ch, ok := m.subscribers[sub]; ok {
+		delete(m.subscribers, sub)
+		close(ch)
+	}
+}
+
+// publish sends the given update to all of the subscribers
+func (m *Monitor) publish(u barrelman.CheckUpdate) {
+	m.subMu.Lock()
+	defer m.subMu.Unlock()
+
+	for _, ch := range m.subscribers {
+		select {
+		case ch <- u:
+		default:
+			log.Printf("Subscriber is full, dropping update for device %s\n", u.Device.Name)
 		}
 	}
 }
 
 // RegisterChecker registers the given checker under the given name to be run on
-// all devices registered in this monitor on the given interval (measured in seconds)
+// all devices registered in this monitor on the given interval (measured in seconds).
+// Devices can disable the checker or override its interval through their
+// CheckerConfig for the checker's name
 func (m *Monitor) RegisterChecker(name string, interval int, c barrelman.Checker) error {
+	m.checkerMu.Lock()
+	defer m.checkerMu.Unlock()
+
 	// Check for existing checker
 	if _, ok := m.checkers[name]; ok {
 		return fmt.Errorf("Checker already registered with name %s", name)
@@ -118,14 +240,62 @@ func (wc *wrappedChecker) Check(d *barrelman.Device, recheck bool) barrelman.Che
 // RegisterDevice registers the given device to have all the registered checks
 // run against it on an interval
 func (m *Monitor) RegisterDevice(d *barrelman.Device) error {
-	// Register device
 	m.deviceMu.Lock()
+	defer m.deviceMu.Unlock()
+
+	// Check for existing device
+	if _, ok := m.devices[d.Name]; ok {
+		return fmt.Errorf("Device already registered with name %s", d.Name)
+	}
+
+	// Register device
 	m.devices[d.Name] = barrelman.DeviceStatus{
 		Device:      d,
 		Healthy:     false,
 		CheckStatus: make(map[string]barrelman.CheckResult),
 	}
-	m.deviceMu.Unlock()
+
+	return nil
+}
+
+// UnregisterDevice stops all checks from being run against the previously
+// registered device by its name and forgets its status. Results of checks
+// already in progress on the device are discarded
+func (m *Monitor) UnregisterDevice(name string) error {
+	m.deviceMu.Lock()
+	defer m.deviceMu.Unlock()
+
+	if _, ok := m.devices[name]; !ok {
+		return fmt.Errorf("No device found with name %s", name)
+	}
+
+	delete(m.devices, name)
+	return nil
+}
+
+// UpdateDevice replaces the previously registered device with the same name
+// as the given device. The results of previous checks on the device are kept
+func (m *Monitor) UpdateDevice(d *barrelman.Device) error {
+	checkers := m.checkerNames()
+
+	m.deviceMu.Lock()
+	defer m.deviceMu.Unlock()
+
+	status, ok := m.devices[d.Name]
+	if !ok {
+		return fmt.Errorf("No device found with name %s", d.Name)
+	}
+
+	// The checkers enabled on the device may have changed, so drop the results
+	// of any that are no longer enabled and recompute its health
+	status.Device = d
+	for name := range status.CheckStatus {
+		if !d.Config(name).Enabled() {
+			delete(status.CheckStatus, name)
+		}
+	}
+	status.Healthy = m.healthPolicy(enabledCheckers(d, checkers), status.CheckStatus)
+	m.devices[d.Name] = status
 
 	return nil
 }
@@ -133,12 +303,22 @@ func (m *Monitor) RegisterDevice(d *barrelman.Device) error {
 // ForceCheck forces the monitor to immediately run all registered checkers against
 // the previously registered device by its name
 func (m *Monitor) ForceCheck(name string) error {
-	if _, ok := m.devices[name]; ok {
-		m.check(name)
-		return nil
+	m.deviceMu.RLock()
+	_, ok := m.devices[name]
+	m.deviceMu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("No device found with name %s", name)
 	}
 
-	return fmt.Errorf("No device found with name %s", name)
+	m.check(name)
+
+	// Wait for the results to be written to the device state
+	flushed := make(chan struct{})
+	m.checkStateChan <- deviceCheckMsg{flushed: flushed}
+	<-flushed
+
+	return nil
 }
 
 // check is the internal function to run all checks on a device
@@ -148,41 +328,149 @@ func (m *Monitor) check(deviceName string) {
 	d := m.devices[deviceName]
 	m.deviceMu.RUnlock()
 
+	// Get the checkers enabled on the device
+	m.checkerMu.RLock()
+	checkers := make([]barrelman.Checker, 0, len(m.checkers))
+	for name, c := range m.checkers {
+		if d.Device.Config(name).Enabled() {
+			checkers = append(checkers, c)
+		}
+	}
+	m.checkerMu.RUnlock()
+
 	// Run all checkers
-	for _, c := range m.checkers {
+	for _, c := range checkers {
 		c.Check(d.Device, true)
 	}
 }
 
+// checkerNames returns the names of all the registered checkers
+func (m *Monitor) checkerNames() []string {
+	m.checkerMu.RLock()
+	defer m.checkerMu.RUnlock()
+
+	names := make([]string, 0, len(m.checkers))
+	for name := range m.checkers {
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// enabledCheckers filters the given checker names down to those which are
+// enabled on the given device
+func enabledCheckers(d *barrelman.Device, checkers []string) []string {
+	enabled := make([]string, 0, len(checkers))
+	for _, name := range checkers {
+		if d.Config(name).Enabled() {
+			enabled = append(enabled, name)
+		}
+	}
+
+	return enabled
+}
+
 // Status will return the current status of the given device (by name)
 func (m *Monitor) Status(name string) (barrelman.DeviceStatus, error) {
 	m.deviceMu.RLock()
-	status, ok := m.devices[name]
-	m.deviceMu.RUnlock()
+	defer m.deviceMu.RUnlock()
 
-	if ok {
-		return status, nil
+	if status, ok := m.devices[name]; ok {
+		return copyStatus(status), nil
 	}
 
 	return barrelman.DeviceStatus{}, fmt.Errorf("No device found with name %s", name)
 }
 
+// AllDevices will return the current status of every registered device
+func (m *Monitor) AllDevices() []barrelman.DeviceStatus {
+	m.deviceMu.RLock()
+	defer m.deviceMu.RUnlock()
+
+	statuses := make([]barrelman.DeviceStatus, 0, len(m.devices))
+	for _, status := range m.devices {
+		statuses = append(statuses, copyStatus(status))
+	}
+
+	return statuses
+}
+
+// copyStatus returns a copy of the given status that is safe to hand out
+// while the monitor continues to write check results. The caller must hold
+// at least a read lock on the devices
+func copyStatus(s barrelman.DeviceStatus) barrelman.DeviceStatus {
+	checks := make(map[string]barrelman.CheckResult, len(s.CheckStatus))
+	for name, result := range s.CheckStatus {
+		checks[name] = result
+	}
+
+	s.CheckStatus = checks
+	return s
+}
+
 // intervalChecker is the internal function used to continuously run a checker
-// on all devices at the configured interval and jitter
+// on all devices at the configured interval and jitter. Each device is
+// scheduled separately so that devices can override the interval and so that
+// checks are spread out rather than all run at once
 func (m *Monitor) intervalChecker(c *wrappedChecker, interval int) {
-	for {
-		// Sleep for the standard interval minus a random number of seconds from
-		// 0 to m.jitter. This helps to distribute large amounts of checks better
-		interval := time.Duration(interval-rand.Intn(m.jitter)) * time.Second
-		log.Printf("Sleeping checker %s for %s\n", c.name, interval)
-		time.Sleep(interval)
-
-		// Initiate checks on all devices
-		log.Printf("Checker %s ranging devices\n", c.name)
+	// schedules is when the checker should next run on each device
+	schedules := make(map[string]schedule)
+
+	for now := range time.Tick(_schedulerTick) {
 		m.deviceMu.RLock()
-		for _, d := range m.devices {
+		for name, d := range m.devices {
+			conf := d.Device.Config(c.name)
+			if !conf.Enabled() {
+				continue
+			}
+
+			devInterval := interval
+			if i, ok := conf.Int(barrelman.ConfigInterval); ok && i > 0 {
+				devInterval = i
+			}
+
+			// Schedule devices the first time they are seen, or again if their
+			// interval has been updated
+			sched, ok := schedules[name]
+			if !ok || sched.interval != devInterval {
+				schedules[name] = schedule{
+					next:     m.nextRun(now, devInterval),
+					interval: devInterval,
+				}
+				continue
+			}
+
+			// Allow for the tick arriving slightly early
+			if sched.next.Sub(now) > _schedulerTick/2 {
+				continue
+			}
+
+			sched.next = m.nextRun(now, devInterval)
+			schedules[name] = sched
 			go c.Check(d.Device, false)
 		}
+
+		// Forget about devices that are no longer registered
+		for name := range schedules {
+			if _, ok := m.devices[name]; !ok {
+				delete(schedules, name)
+			}
+		}
 		m.deviceMu.RUnlock()
 	}
 }
+
+// nextRun returns the next time a check should be run given the interval (in
+// seconds). A random number of seconds from 0 to m.jitter is subtracted from
+// the interval, which helps to distribute large amounts of checks better
+func (m *Monitor) nextRun(now time.Time, interval int) time.Time {
+	if m.jitter > 0 {
+		interval -= rand.Intn(m.jitter)
+	}
+
+	if interval < 1 {
+		interval = 1
+	}
+
+	return now.Add(time.Duration(interval) * time.Second)
+}
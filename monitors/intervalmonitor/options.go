@@ -21,3 +21,11 @@ func WithEventEmitter(e barrelman.EventEmitter) Option {
 		m.eventEmitter = e
 	}
 }
+
+// WithHealthPolicy allows the user to set the policy used to decide whether a
+// device is healthy based on its check results. The default is AllPassing
+func WithHealthPolicy(p HealthPolicy) Option {
+	return func(m *Monitor) {
+		m.healthPolicy = p
+	}
+}
@@ -0,0 +1,55 @@
+package intervalmonitor
+
+import "github.com/byuoitav/barrelman"
+
+// HealthPolicy decides whether a device is healthy. It is given the names of
+// all of the checkers registered with the monitor and the latest result of
+// each checker that has been run on the device
+type HealthPolicy func(checkers []string, results map[string]barrelman.CheckResult) bool
+
+// AllPassing returns a HealthPolicy which considers a device healthy only if
+// every registered checker has run on the device and passed or was skipped.
+// This is the default policy
+func AllPassing() HealthPolicy {
+	return func(checkers []string, results map[string]barrelman.CheckResult) bool {
+		for _, name := range checkers {
+			if result, ok := results[name]; !ok || result.Failed() {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// CriticalPassing returns a HealthPolicy which considers a device healthy if
+// each of the given critical checkers has run on the device and passed or was
+// skipped. The results of all other checkers are ignored
+func CriticalPassing(critical ...string) HealthPolicy {
+	return func(checkers []string, results map[string]barrelman.CheckResult) bool {
+		for _, name := range critical {
+			if result, ok := results[name]; !ok || result.Failed() {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// NOfM returns a HealthPolicy which considers a device healthy if at least n
+// of the registered checkers have run on the device and passed. Skipped
+// checkers don't count toward n, since a checker is usually skipped because
+// one it depends on has failed
+func NOfM(n int) HealthPolicy {
+	return func(checkers []string, results map[string]barrelman.CheckResult) bool {
+		passed := 0
+		for _, name := range checkers {
+			if result, ok := results[name]; ok && result.Passed {
+				passed++
+			}
+		}
+
+		return passed >= n
+	}
+}
@@ -0,0 +1,53 @@
+package intervalmonitor
+
+import (
+	"testing"
+
+	"github.com/byuoitav/barrelman"
+)
+
+var (
+	_passed  = barrelman.CheckResult{Passed: true}
+	_failed  = barrelman.CheckResult{Error: "Device is unreachable"}
+	_skipped = barrelman.CheckResult{Skipped: true, Message: "Skipped because ping is failing"}
+)
+
+func TestHealthPolicies(t *testing.T) {
+	checkers := []string{"ping", "health"}
+
+	tests := []struct {
+		name    string
+		policy  HealthPolicy
+		results map[string]barrelman.CheckResult
+		want    bool
+	}{
+		{"all passing", AllPassing(), map[string]barrelman.CheckResult{"ping": _passed, "health": _passed}, true},
+		{"all passing with failure", AllPassing(), map[string]barrelman.CheckResult{"ping": _passed, "health": _failed}, false},
+		{"all passing with skip", AllPassing(), map[string]barrelman.CheckResult{"ping": _passed, "health": _skipped}, true},
+		{"all passing with skip after failure", AllPassing(), map[string]barrelman.CheckResult{"ping": _failed, "health": _skipped}, false},
+		{"all passing not run", AllPassing(), map[string]barrelman.CheckResult{"ping": _passed}, false},
+		{"all passing no results", AllPassing(), map[string]barrelman.CheckResult{}, false},
+
+		{"critical passing", CriticalPassing("ping"), map[string]barrelman.CheckResult{"ping": _passed, "health": _failed}, true},
+		{"critical failing", CriticalPassing("ping"), map[string]barrelman.CheckResult{"ping": _failed, "health": _passed}, false},
+		{"critical skipped", CriticalPassing("health"), map[string]barrelman.CheckResult{"ping": _passed, "health": _skipped}, true},
+		{"critical not run", CriticalPassing("health"), map[string]barrelman.CheckResult{"ping": _passed}, false},
+		{"no critical checkers", CriticalPassing(), map[string]barrelman.CheckResult{"ping": _failed}, true},
+
+		{"1 of 2 passing", NOfM(1), map[string]barrelman.CheckResult{"ping": _passed, "health": _failed}, true},
+		{"2 of 2 with 1 passing", NOfM(2), map[string]barrelman.CheckResult{"ping": _passed, "health": _failed}, false},
+		{"1 of 2 with skip after failure", NOfM(1), map[string]barrelman.CheckResult{"ping": _failed, "health": _skipped}, false},
+		{"2 of 2 with skip", NOfM(2), map[string]barrelman.CheckResult{"ping": _passed, "health": _skipped}, false},
+		{"1 of 2 not run", NOfM(1), map[string]barrelman.CheckResult{}, false},
+		{"0 of 2", NOfM(0), map[string]barrelman.CheckResult{}, true},
+		{"unregistered checker ignored", NOfM(1), map[string]barrelman.CheckResult{"tcp": _passed, "ping": _failed}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy(checkers, tt.results); got != tt.want {
+				t.Errorf("got healthy %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package roommonitor
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/byuoitav/barrelman"
+)
+
+var _ barrelman.RoomMonitor = (*Monitor)(nil)
+
+// Monitor rolls up the status of the devices in a DeviceMonitor into a
+// status for each room, grouping the devices by their Room
+type Monitor struct {
+	devices barrelman.DeviceMonitor
+}
+
+// NewMonitor returns a new RoomMonitor which reports on the rooms of the
+// devices registered with the given DeviceMonitor
+func NewMonitor(dm barrelman.DeviceMonitor) (*Monitor, error) {
+	if dm == nil {
+		return nil, fmt.Errorf("A device monitor is required")
+	}
+
+	return &Monitor{
+		devices: dm,
+	}, nil
+}
+
+// RoomStatus returns the current status of the given room. An error is
+// returned if there are no monitored devices in the room
+func (m *Monitor) RoomStatus(roomID string) (barrelman.RoomStatus, error) {
+	devs := []barrelman.DeviceStatus{}
+	for _, d := range m.devices.AllDevices() {
+		if d.Device.Room == roomID {
+			devs = append(devs, d)
+		}
+	}
+
+	if len(devs) == 0 {
+		return barrelman.RoomStatus{}, fmt.Errorf("No devices found in room %s", roomID)
+	}
+
+	return rollUp(roomID, devs), nil
+}
+
+// AllRooms returns the current status of every room that has at least one
+// monitored device, sorted by room ID
+func (m *Monitor) AllRooms() []barrelman.RoomStatus {
+	rooms := make(map[string][]barrelman.DeviceStatus)
+	for _, d := range m.devices.AllDevices() {
+		rooms[d.Device.Room] = append(rooms[d.Device.Room], d)
+	}
+
+	statuses := make([]barrelman.RoomStatus, 0, len(rooms))
+	for room, devs := range rooms {
+		statuses = append(statuses, rollUp(room, devs))
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Room < statuses[j].Room
+	})
+
+	return statuses
+}
+
+// rollUp aggregates the given device statuses into a single room status.
+// A room is healthy if all of its devices are healthy, down if none of them
+// are, and degraded otherwise
+func rollUp(room string, devs []barrelman.DeviceStatus) barrelman.RoomStatus {
+	sort.Slice(devs, func(i, j int) bool {
+		return devs[i].Device.Name < devs[j].Device.Name
+	})
+
+	status := barrelman.RoomStatus{
+		Room:            room,
+		DeviceCount:     len(devs),
+		FailingCheckers: make(map[string][]string),
+		Devices:         devs,
+	}
+
+	for _, d := range devs {
+		if d.Healthy {
+			status.HealthyCount++
+		} else {
+			status.UnhealthyCount++
+		}
+
+		for name, result := range d.CheckStatus {
+			if result.Failed() {
+				status.FailingCheckers[name] = append(status.FailingCheckers[name], d.Device.Name)
+			}
+		}
+	}
+
+	switch {
+	case status.UnhealthyCount == 0:
+		status.State = barrelman.RoomHealthy
+	case status.HealthyCount == 0:
+		status.State = barrelman.RoomDown
+	default:
+		status.State = barrelman.RoomDegraded
+	}
+
+	return status
+}